@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/xsolrac87/booking/booking"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -17,6 +17,8 @@ const (
 type HttpServer struct {
 	*http.Server
 	timeout time.Duration
+	handler *booking.Handler
+	logger  *slog.Logger
 }
 
 func NewHTTPServer(addr string, opts ...Option) (*HttpServer, error) {
@@ -42,34 +44,89 @@ func NewHTTPServer(addr string, opts ...Option) (*HttpServer, error) {
 		timeout = defaultTimeout
 	}
 
+	if options.logger == nil {
+		options.logger = slog.Default()
+	}
+
+	handler, err := newBookingHandler(options)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &HttpServer{
 		Server: &http.Server{
-			Addr: fmt.Sprintf("%s:%d", addr, port),
+			Addr:     fmt.Sprintf("%s:%d", addr, port),
+			ErrorLog: slog.NewLogLogger(options.logger.Handler(), slog.LevelError),
 		},
 		timeout: timeout,
+		handler: handler,
+		logger:  options.logger,
 	}
-
-	s.registerRoutes()
+	s.Server.Handler = chain(newRouter(s.routes(), handler.HandlerMethodNotAllowed, handler.HandlerNotFound), middlewares(options, handler)...)
 	return s, nil
 }
 
+// Run serves until ctx is done, then shuts the server down gracefully. It fails if the server cannot serve.
 func (s *HttpServer) Run(ctx context.Context) error {
+	errs := make(chan error, 1)
 	go func() {
-		log.Println("HTTP Server running on", s.Addr)
+		s.logger.Info("HTTP server running", "addr", s.Addr)
 		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("HTTP server shut down", err)
+			errs <- err
 		}
 	}()
 
-	<-ctx.Done()
+	select {
+	case err := <-errs:
+		return fmt.Errorf("HTTP server shut down: %w", err)
+	case <-ctx.Done():
+	}
 	ctxShutDown, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
-	log.Println("shutting down HTTP server")
+	s.logger.Info("shutting down HTTP server")
 	return s.Shutdown(ctxShutDown)
 }
 
-func (s *HttpServer) registerRoutes() {
-	// Booking
-	http.HandleFunc("/stats", booking.HandleR.HandlerStats)
-	http.HandleFunc("/maximize", booking.HandleR.HandlerMaximize)
+// middlewares returns the middlewares of every route, outermost first: the request ID, so every other one can log it,
+// the access log, seeing the responses of the recovered panics, the optional body limit and compression, and last
+// the ones of the options.
+func middlewares(options options, handler *booking.Handler) []Middleware {
+	m := []Middleware{
+		requestID(),
+		accessLog(options.logger),
+		recoverPanics(options.logger, http.HandlerFunc(handler.HandlerInternalError)),
+	}
+	if options.maxBodySize != nil {
+		m = append(m, limitBody(*options.maxBodySize))
+	}
+	if options.gzipLevel != nil {
+		m = append(m, compress(options.logger, *options.gzipLevel))
+	}
+	return append(m, options.middlewares...)
+}
+
+func newBookingHandler(options options) (*booking.Handler, error) {
+	var (
+		statsOpts    = []booking.StatsOption{booking.WithStatsLogger(options.logger)}
+		maximizeOpts = []booking.MaximizeOption{booking.WithLogger(options.logger)}
+	)
+	if options.bufferNights != nil {
+		maximizeOpts = append(maximizeOpts, booking.WithBufferNights(*options.bufferNights))
+	}
+	if options.rates != nil {
+		statsOpts = append(statsOpts, booking.WithStatsRates(options.rates))
+		maximizeOpts = append(maximizeOpts, booking.WithRates(options.rates))
+	}
+
+	stats, err := booking.NewStatsService(statsOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	maximize, err := booking.NewMaximizeService(stats, maximizeOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return booking.NewHandler(stats, maximize, booking.WithHandlerLogger(options.logger))
 }
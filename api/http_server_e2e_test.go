@@ -5,8 +5,11 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/xsolrac87/booking/booking"
+	"github.com/xsolrac87/booking/money"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -14,8 +17,14 @@ import (
 )
 
 func TestAPI_Booking(t *testing.T) {
+	handler, err := newBookingHandler(options{logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	tests := map[string]struct {
 		payload            []byte
+		contentType        string
 		endpoint           string
 		handlerFunc        func(w http.ResponseWriter, req *http.Request)
 		validateResponse   func([]byte, interface{}, *testing.T)
@@ -41,12 +50,33 @@ func TestAPI_Booking(t *testing.T) {
 					}
 				]
 			`),
-			handlerFunc:      booking.HandleR.HandlerStats,
+			handlerFunc:      handler.HandlerStats,
+			validateResponse: validateStatsResponse,
+			expected: booking.ProfitPerNight{
+				AvgNight: money.MustParse("8.29"),
+				MinNight: money.FromUnits(8),
+				MaxNight: money.MustParse("8.58"),
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		"stats e2e call with decimal selling rates": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 3,
+  						"selling_rate": 199.99,
+  						"margin": 15
+					}
+				]
+			`),
+			handlerFunc:      handler.HandlerStats,
 			validateResponse: validateStatsResponse,
 			expected: booking.ProfitPerNight{
-				AvgNight: 8.29,
-				MinNight: 8,
-				MaxNight: 8.58,
+				AvgNight: money.FromUnits(10),
+				MinNight: money.FromUnits(10),
+				MaxNight: money.FromUnits(10),
 			},
 			expectedStatusCode: http.StatusOK,
 		},
@@ -68,17 +98,17 @@ func TestAPI_Booking(t *testing.T) {
 					}
 				]
 			`),
-			handlerFunc:        booking.HandleR.HandlerStats,
+			handlerFunc:        handler.HandlerStats,
 			validateResponse:   nil,
 			expected:           nil,
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		"stats e2e call invalid payload": {
 			payload:            []byte(""),
-			handlerFunc:        booking.HandleR.HandlerStats,
+			handlerFunc:        handler.HandlerStats,
 			validateResponse:   nil,
 			expected:           nil,
-			expectedStatusCode: http.StatusInternalServerError,
+			expectedStatusCode: http.StatusBadRequest,
 		},
 		"maximize e2e call with pdf example": {
 			payload: []byte(`
@@ -106,15 +136,35 @@ func TestAPI_Booking(t *testing.T) {
 					}
 				]
 			`),
-			handlerFunc:      booking.HandleR.HandlerMaximize,
+			handlerFunc:      handler.HandlerMaximize,
 			validateResponse: validateMaximizeResponse,
 			expected: booking.MaximizeProfit{
 				RequestIDS:  []string{"A", "C"},
-				TotalProfit: 140,
+				TotalProfit: money.FromUnits(140),
 				ProfitPerNight: booking.ProfitPerNight{
-					AvgNight: 7,
-					MinNight: 4,
-					MaxNight: 10,
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		"maximize e2e call with csv payload": {
+			payload: []byte("request_id;check_in;nights;selling_rate;margin;partner\n" +
+				"A;2018-01-01;10;1000;10;acme\n" +
+				"B;2018-01-06;10;700;10;acme\n" +
+				"C;2018-01-12;10;400;10;acme\n"),
+			contentType:      "text/csv; charset=utf-8",
+			endpoint:         "/maximize?delimiter=%3B",
+			handlerFunc:      handler.HandlerMaximize,
+			validateResponse: validateMaximizeResponse,
+			expected: booking.MaximizeProfit{
+				RequestIDS:  []string{"A", "C"},
+				TotalProfit: money.FromUnits(140),
+				ProfitPerNight: booking.ProfitPerNight{
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
 				},
 			},
 			expectedStatusCode: http.StatusOK,
@@ -137,17 +187,17 @@ func TestAPI_Booking(t *testing.T) {
 					}
 				]
 			`),
-			handlerFunc:        booking.HandleR.HandlerMaximize,
+			handlerFunc:        handler.HandlerMaximize,
 			validateResponse:   nil,
 			expected:           booking.MaximizeProfit{},
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		"maximize e2e call with invalid payload": {
 			payload:            []byte(""),
-			handlerFunc:        booking.HandleR.HandlerMaximize,
+			handlerFunc:        handler.HandlerMaximize,
 			validateResponse:   nil,
 			expected:           booking.MaximizeProfit{},
-			expectedStatusCode: http.StatusInternalServerError,
+			expectedStatusCode: http.StatusBadRequest,
 		},
 	}
 
@@ -157,7 +207,7 @@ func TestAPI_Booking(t *testing.T) {
 			t.Parallel()
 
 			server := httptest.NewServer(http.HandlerFunc(tt.handlerFunc))
-			resp, err := http.Post(server.URL+tt.endpoint, "", bytes.NewBuffer(tt.payload))
+			resp, err := http.Post(server.URL+tt.endpoint, tt.contentType, bytes.NewBuffer(tt.payload))
 			if err != nil {
 				t.Error(err)
 			}
@@ -219,3 +269,89 @@ func validateMaximizeResponse(b []byte, e interface{}, t *testing.T) {
 		t.Errorf("got: %v, expected: %v", got.ProfitPerNight, expected.ProfitPerNight)
 	}
 }
+
+func TestHTTPServer_Routes(t *testing.T) {
+	s, err := NewHTTPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s.Handler)
+	t.Cleanup(server.Close)
+
+	payload := `[{"request_id": "A", "check_in": "2020-01-01", "nights": 5, "selling_rate": 200, "margin": 20}]`
+
+	tests := map[string]struct {
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedAllow      string
+		expectedLink       string
+		expectedCode       string
+	}{
+		"stats":                    {method: http.MethodPost, path: "/v1/booking/stats", expectedStatusCode: http.StatusOK},
+		"histogram":                {method: http.MethodPost, path: "/v1/booking/stats/histogram?quantiles=1", expectedStatusCode: http.StatusOK},
+		"maximize":                 {method: http.MethodPost, path: "/v1/booking/maximize", expectedStatusCode: http.StatusOK},
+		"frontier":                 {method: http.MethodPost, path: "/v1/booking/maximize/frontier", expectedStatusCode: http.StatusOK},
+		"calendar":                 {method: http.MethodPost, path: "/v1/booking/calendar", expectedStatusCode: http.StatusOK},
+		"stats alias":              {method: http.MethodPost, path: "/stats", expectedStatusCode: http.StatusOK, expectedLink: "/v1/booking/stats"},
+		"maximize alias":           {method: http.MethodPost, path: "/maximize", expectedStatusCode: http.StatusOK, expectedLink: "/v1/booking/maximize"},
+		"method not allowed":       {method: http.MethodGet, path: "/v1/booking/stats", expectedStatusCode: http.StatusMethodNotAllowed, expectedAllow: http.MethodPost},
+		"alias method not allowed": {method: http.MethodPut, path: "/maximize", expectedStatusCode: http.StatusMethodNotAllowed, expectedAllow: http.MethodPost, expectedLink: "/v1/booking/maximize"},
+		"unknown route":            {method: http.MethodPost, path: "/v1/booking/unknown", expectedStatusCode: http.StatusNotFound, expectedCode: "not_found"},
+		"root":                     {method: http.MethodGet, path: "/", expectedStatusCode: http.StatusNotFound, expectedCode: "not_found"},
+		"no histogram alias":       {method: http.MethodPost, path: "/stats/histogram?quantiles=1", expectedStatusCode: http.StatusNotFound, expectedCode: "not_found"},
+		"no frontier alias":        {method: http.MethodPost, path: "/maximize/frontier", expectedStatusCode: http.StatusNotFound, expectedCode: "not_found"},
+		"no calendar alias":        {method: http.MethodPost, path: "/calendar", expectedStatusCode: http.StatusNotFound, expectedCode: "not_found"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, bytes.NewBufferString(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatusCode {
+				t.Errorf("got HTTP status code %d, expected %d", resp.StatusCode, tt.expectedStatusCode)
+			}
+			if allow := resp.Header.Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("got Allow %q, expected %q", allow, tt.expectedAllow)
+			}
+
+			// only the aliases are deprecated, linking to their successor.
+			var expectedDeprecation, expectedLink string
+			if tt.expectedLink != "" {
+				expectedDeprecation = "@1792281600"
+				expectedLink = fmt.Sprintf(`<%s>; rel="successor-version"`, tt.expectedLink)
+			}
+			if deprecation := resp.Header.Get("Deprecation"); deprecation != expectedDeprecation {
+				t.Errorf("got Deprecation %q, expected %q", deprecation, expectedDeprecation)
+			}
+			if link := resp.Header.Get("Link"); link != expectedLink {
+				t.Errorf("got Link %q, expected %q", link, expectedLink)
+			}
+
+			if tt.expectedCode != "" {
+				if contentType := resp.Header.Get("Content-Type"); contentType != "application/problem+json" {
+					t.Errorf("got Content-Type %q, expected application/problem+json", contentType)
+				}
+				var p booking.Problem
+				err = json.NewDecoder(resp.Body).Decode(&p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.Code != tt.expectedCode {
+					t.Errorf("got code %s, expected %s", p.Code, tt.expectedCode)
+				}
+			}
+		})
+	}
+}
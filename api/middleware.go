@@ -0,0 +1,225 @@
+package api
+
+import (
+	"compress/gzip"
+	"github.com/xsolrac87/booking/booking"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRequestIDLength caps the request IDs taken from clients, which end up in every log line of the request.
+const maxRequestIDLength = 128
+
+// Middleware wraps the handler of every route with a concern shared by all of them, like logging or compression.
+type Middleware func(next http.Handler) http.Handler
+
+// chain returns h wrapped by middlewares, the first one being the outermost, so the first to see the requests.
+func chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// statusWriter records the status and size of the response written through it, 0 until it starts.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// requestID gives every request an ID, the one sent by the client in the X-Request-ID header when it is printable
+// ASCII of up to maxRequestIDLength characters, or a random one, and answers it in the same header.
+func requestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(booking.RequestIDHeader)
+			if !validRequestID(id) {
+				id = booking.NewRequestID()
+				req.Header.Set(booking.RequestIDHeader, id)
+			}
+			w.Header().Set(booking.RequestIDHeader, id)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// accessLog logs every request once answered, with its status, the size of its response and its latency.
+func accessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				status := sw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				requestLogger(logger, req).Info("request answered",
+					"method", req.Method,
+					"query", req.URL.RawQuery,
+					"status", status,
+					"bytes", sw.size,
+					"duration", time.Since(start),
+				)
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
+
+// requestLogger returns logger with the request ID and the endpoint of req.
+func requestLogger(logger *slog.Logger, req *http.Request) *slog.Logger {
+	return logger.With("request_id", req.Header.Get(booking.RequestIDHeader), "endpoint", req.URL.Path)
+}
+
+// recoverPanics answers the requests whose handling panicked with fallback, logging the panic and its stack. A
+// response already started cannot be answered anymore, so its connection is aborted instead of ending it as if it
+// were complete.
+func recoverPanics(logger *slog.Logger, fallback http.Handler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				requestLogger(logger, req).Error("request panicked", "panic", v, "stack", string(debug.Stack()))
+				if sw.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				fallback.ServeHTTP(w, req)
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
+
+// limitBody fails reading the bodies of the requests past limit bytes, which the booking handler answers with a 413.
+func limitBody(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			req.Body = http.MaxBytesReader(w, req.Body, limit)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// compress gzips the responses with a body of the requests accepting it, at level.
+func compress(logger *slog.Logger, level int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			gw := &gzipWriter{ResponseWriter: w, level: level, accepted: acceptsGzip(req.Header.Get("Accept-Encoding"))}
+			defer func() {
+				err := gw.close()
+				if err != nil {
+					requestLogger(logger, req).Warn("failed to compress response", "error", err)
+				}
+			}()
+			next.ServeHTTP(gw, req)
+		})
+	}
+}
+
+// acceptsGzip tells whether the Accept-Encoding header acceptEncoding accepts gzip, by name or else by a wildcard.
+func acceptsGzip(acceptEncoding string) bool {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) == "q" {
+				q, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			}
+		}
+		accepted[coding] = q > 0
+	}
+
+	if ok, named := accepted["gzip"]; named {
+		return ok
+	}
+	return accepted["*"]
+}
+
+// gzipWriter compresses the body of a response once its headers tell it has one and it is not encoded yet.
+type gzipWriter struct {
+	http.ResponseWriter
+	level       int
+	accepted    bool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if w.accepted && h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		// the level is validated by the option.
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipWriter) close() error {
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Close()
+}
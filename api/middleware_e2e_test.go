@@ -0,0 +1,289 @@
+//go:build e2e
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/xsolrac87/booking/booking"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTTPServer_Middlewares(t *testing.T) {
+	payload := `[{"request_id": "A", "check_in": "2020-01-01", "nights": 5, "selling_rate": 200, "margin": 20}]`
+	panics := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Query().Get("panic") == "true" {
+				panic("handler failed")
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+	generatedID := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := map[string]struct {
+		opts                    []Option
+		path                    string
+		header                  http.Header
+		payload                 string
+		expectedStatusCode      int
+		expectedRequestID       *regexp.Regexp
+		expectedContentType     string
+		expectedContentEncoding string
+		expectedCode            string
+	}{
+		"generated request ID": {
+			path:                "/v1/booking/stats",
+			payload:             payload,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/json",
+		},
+		"propagated request ID": {
+			path:                "/v1/booking/stats",
+			header:              http.Header{"X-Request-Id": {"client-42"}},
+			payload:             payload,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestID:   regexp.MustCompile(`^client-42$`),
+			expectedContentType: "application/json",
+		},
+		"replaced request ID": {
+			path:                "/v1/booking/stats",
+			header:              http.Header{"X-Request-Id": {"client 42"}},
+			payload:             payload,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/json",
+		},
+		"request ID of problems": {
+			path:                "/v1/booking/stats",
+			header:              http.Header{"X-Request-Id": {"client-42"}},
+			payload:             "[]",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedRequestID:   regexp.MustCompile(`^client-42$`),
+			expectedContentType: "application/problem+json",
+			expectedCode:        "no_bookings",
+		},
+		"recovered panic": {
+			opts:                []Option{WithMiddleware(panics)},
+			path:                "/v1/booking/stats?panic=true",
+			payload:             payload,
+			expectedStatusCode:  http.StatusInternalServerError,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/problem+json",
+			expectedCode:        "internal_error",
+		},
+		"gzip": {
+			opts:                    []Option{WithGzip(gzip.BestSpeed)},
+			path:                    "/v1/booking/stats",
+			header:                  http.Header{"Accept-Encoding": {"br, gzip;q=0.5"}},
+			payload:                 payload,
+			expectedStatusCode:      http.StatusOK,
+			expectedRequestID:       generatedID,
+			expectedContentType:     "application/json",
+			expectedContentEncoding: "gzip",
+		},
+		"gzip problems": {
+			opts:                    []Option{WithGzip(gzip.DefaultCompression)},
+			path:                    "/v1/booking/stats",
+			header:                  http.Header{"Accept-Encoding": {"*"}},
+			payload:                 "[]",
+			expectedStatusCode:      http.StatusBadRequest,
+			expectedRequestID:       generatedID,
+			expectedContentType:     "application/problem+json",
+			expectedContentEncoding: "gzip",
+			expectedCode:            "no_bookings",
+		},
+		"gzip not accepted": {
+			opts:                []Option{WithGzip(gzip.BestSpeed)},
+			path:                "/v1/booking/stats",
+			header:              http.Header{"Accept-Encoding": {"gzip;q=0, identity"}},
+			payload:             payload,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/json",
+		},
+		"body within the limit": {
+			opts:                []Option{WithMaxBodySize(int64(len(payload)))},
+			path:                "/v1/booking/stats",
+			payload:             payload,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/json",
+		},
+		"body over the limit": {
+			opts:                []Option{WithMaxBodySize(16)},
+			path:                "/v1/booking/stats",
+			payload:             payload,
+			expectedStatusCode:  http.StatusRequestEntityTooLarge,
+			expectedRequestID:   generatedID,
+			expectedContentType: "application/problem+json",
+			expectedCode:        "body_too_large",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := NewHTTPServer("", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(s.Handler)
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+tt.path, bytes.NewBufferString(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			if req.Header.Get("Accept-Encoding") == "" {
+				// the transport would ask for gzip and decompress the response itself.
+				req.Header.Set("Accept-Encoding", "identity")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatusCode {
+				t.Errorf("got HTTP status code %d, expected %d", resp.StatusCode, tt.expectedStatusCode)
+			}
+			id := resp.Header.Get(booking.RequestIDHeader)
+			if !tt.expectedRequestID.MatchString(id) {
+				t.Errorf("got request ID %q, expected it to match %s", id, tt.expectedRequestID)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != tt.expectedContentType {
+				t.Errorf("got content type %s, expected %s", ct, tt.expectedContentType)
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != tt.expectedContentEncoding {
+				t.Errorf("got content encoding %q, expected %q", ce, tt.expectedContentEncoding)
+			}
+
+			var body io.Reader = resp.Body
+			if tt.expectedContentEncoding == "gzip" {
+				body, err = gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got map[string]interface{}
+			err = json.NewDecoder(body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+			if got["code"] != tt.expectedCode {
+				t.Errorf("got code %v, expected %s", got["code"], tt.expectedCode)
+			}
+			if got["correlation_id"] != id {
+				t.Errorf("got correlation ID %v, expected the request ID %s", got["correlation_id"], id)
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"GZIP":                true,
+		"deflate, gzip":       true,
+		"gzip;q=0":            false,
+		"gzip; q=0.001":       true,
+		"*":                   true,
+		"identity":            false,
+		"br;q=1.0, gzip;q=0":  false,
+		"deflate, *;q=0.5":    true,
+		"identity, *;q=0.000": false,
+		"*;q=0.5, gzip;q=0":   false,
+		"gzip, *;q=0":         true,
+	}
+
+	for acceptEncoding, expected := range tests {
+		acceptEncoding, expected := acceptEncoding, expected
+		t.Run(acceptEncoding, func(t *testing.T) {
+			t.Parallel()
+			if got := acceptsGzip(acceptEncoding); got != expected {
+				t.Errorf("got: %v, expected: %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	named := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "handler")
+	}), named("first"), named("second"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(calls, " "); got != "first second handler" {
+		t.Errorf("got: %s, expected: first second handler", got)
+	}
+}
+
+func TestHTTPServer_Logging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	s, err := NewHTTPServer("", WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := `[{"request_id": "A", "check_in": "2020-01-01", "nights": 5, "selling_rate": 200, "margin": 20}]`
+	wr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/booking/stats?validation=strict", bytes.NewBufferString(payload))
+	req.Header.Set(booking.RequestIDHeader, "client-42")
+	s.Handler.ServeHTTP(wr, req)
+
+	var lines []map[string]interface{}
+	d := json.NewDecoder(&logs)
+	for d.More() {
+		var line map[string]interface{}
+		err = d.Decode(&line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line["request_id"] != "client-42" {
+			t.Errorf("got request ID %v in %q, expected client-42", line["request_id"], line["msg"])
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, expected the access log one alone", len(lines))
+	}
+
+	answered := lines[0]
+	if answered["msg"] != "request answered" || answered["endpoint"] != "/v1/booking/stats" || answered["status"] != 200.0 {
+		t.Errorf("got access log line %v", answered)
+	}
+	if answered["query"] != "validation=strict" || answered["method"] != http.MethodPost {
+		t.Errorf("got access log line %v", answered)
+	}
+	if _, ok := answered["duration"]; !ok {
+		t.Errorf("got no duration in %q", answered["msg"])
+	}
+}
@@ -1,18 +1,32 @@
 package api
 
 import (
+	"compress/gzip"
 	"errors"
+	"github.com/xsolrac87/booking/fx"
+	"log/slog"
 	"time"
 )
 
 var (
-	errPort    = errors.New("port should be positive")
-	errTimeout = errors.New("timeout should be positive")
+	errPort         = errors.New("port should be positive")
+	errTimeout      = errors.New("timeout should be positive")
+	errBufferNights = errors.New("buffer nights should not be negative")
+	errGzipLevel    = errors.New("gzip level should be between -2 and 9")
+	errMaxBodySize  = errors.New("max body size should be positive")
+	errMiddleware   = errors.New("middleware should not be nil")
+	errLogger       = errors.New("logger should not be nil")
 )
 
 type options struct {
-	port    *int
-	timeout *time.Duration
+	port         *int
+	timeout      *time.Duration
+	bufferNights *int
+	rates        fx.RateProvider
+	gzipLevel    *int
+	maxBodySize  *int64
+	middlewares  []Middleware
+	logger       *slog.Logger
 }
 
 type Option func(options *options) error
@@ -36,3 +50,71 @@ func WithTimeout(timeout time.Duration) Option {
 		return nil
 	}
 }
+
+// WithBufferNights sets the default nights a unit stays free between stays on maximize, 0 allowing same-day turnover.
+func WithBufferNights(nights int) Option {
+	return func(options *options) error {
+		if nights < 0 {
+			return errBufferNights
+		}
+		options.bufferNights = &nights
+		return nil
+	}
+}
+
+// WithRates normalises the bookings of every request to a reporting currency with the exchange rates of provider.
+func WithRates(provider fx.RateProvider) Option {
+	return func(options *options) error {
+		options.rates = provider
+		return nil
+	}
+}
+
+// WithGzip compresses the responses of the requests accepting gzip at level, from gzip.HuffmanOnly to
+// gzip.BestCompression.
+func WithGzip(level int) Option {
+	return func(options *options) error {
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return errGzipLevel
+		}
+		options.gzipLevel = &level
+		return nil
+	}
+}
+
+// WithMaxBodySize answers the requests with a body over bytes with a 413.
+func WithMaxBodySize(bytes int64) Option {
+	return func(options *options) error {
+		if bytes <= 0 {
+			return errMaxBodySize
+		}
+		options.maxBodySize = &bytes
+		return nil
+	}
+}
+
+// WithMiddleware adds middlewares to every route, in order, inside the built-in ones: they see the requests with
+// their ID, recovered from panics, with their body limited and their response compressed.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(options *options) error {
+		for _, m := range middlewares {
+			if m == nil {
+				return errMiddleware
+			}
+		}
+		options.middlewares = append(options.middlewares, middlewares...)
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the server, its middlewares, the booking handler and services, slog.Default() by
+// default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) error {
+		if logger == nil {
+			return errLogger
+		}
+		options.logger = logger
+		return nil
+	}
+}
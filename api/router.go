@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the namespace and version every route is mounted under.
+const apiPrefix = "/v1/booking"
+
+// legacyDeprecation is when the legacy routes were deprecated, as their versioned successors came out.
+var legacyDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// route is an endpoint of the API, served at its path under apiPrefix for the methods it allows. Legacy routes, the
+// ones out before the API was versioned, are also served at their path alone, answering with a Deprecation header.
+type route struct {
+	path    string
+	methods []string
+	handler http.HandlerFunc
+	legacy  bool
+}
+
+func (s *HttpServer) routes() []route {
+	post := []string{http.MethodPost}
+	return []route{
+		// Booking
+		{path: "/stats", methods: post, handler: s.handler.HandlerStats, legacy: true},
+		{path: "/stats/histogram", methods: post, handler: s.handler.HandlerHistogram},
+		{path: "/maximize", methods: post, handler: s.handler.HandlerMaximize, legacy: true},
+		{path: "/maximize/frontier", methods: post, handler: s.handler.HandlerFrontier},
+		{path: "/calendar", methods: post, handler: s.handler.HandlerCalendar},
+	}
+}
+
+// newRouter returns a ServeMux serving routes, answering the requests with a method a route does not allow with
+// notAllowed once their Allow header is set, and the ones to any other path with notFound.
+func newRouter(routes []route, notAllowed, notFound http.HandlerFunc) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", notFound)
+	for _, r := range routes {
+		h := allowMethods(r.methods, r.handler, notAllowed)
+		mux.Handle(apiPrefix+r.path, h)
+		if r.legacy {
+			mux.Handle(r.path, deprecated(apiPrefix+r.path, h))
+		}
+	}
+	return mux
+}
+
+func allowMethods(methods []string, next http.Handler, notAllowed http.Handler) http.Handler {
+	allow := strings.Join(methods, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, m := range methods {
+			if req.Method == m {
+				next.ServeHTTP(w, req)
+				return
+			}
+		}
+		w.Header().Set("Allow", allow)
+		notAllowed.ServeHTTP(w, req)
+	})
+}
+
+// deprecated marks the responses of next as deprecated since legacyDeprecation, as a structured date (RFC 9745),
+// linking to the successor path serving them from now on.
+func deprecated(successor string, next http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", legacyDeprecation.Unix())
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		next.ServeHTTP(w, req)
+	})
+}
@@ -1,64 +1,222 @@
 package booking
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
 	"github.com/xsolrac87/booking/timeparser"
+	"math"
+	"strings"
 	"time"
 )
 
+const (
+	// maxSellingRate and maxMargin keep the profit of a valid booking up to 10^12 cents, so the amounts of the up to
+	// maxBookings bookings of a payload add up within an int64. Margins above 100 % are valid, for rates far above
+	// the cost.
+	maxSellingRate = 1_000_000_000
+	maxMargin      = 1000
+)
+
 var (
 	errRequestIDMissing   = errors.New("booking payload does not contain request_id property")
 	errCheckInMissing     = errors.New("booking payload does not contain check_in property")
 	errNightsMissing      = errors.New("booking payload does not contain nights property")
 	errSellingRateMissing = errors.New("booking payload does not contain selling_rate property")
 	errMarginMissing      = errors.New("booking payload does not contain margin property")
+	errSellingRateInvalid = errors.New("booking payload contains an invalid selling_rate")
+	errCheckInInvalid     = errors.New("booking payload contains an invalid check_in")
+	errBookingType        = errors.New("booking payload should be a JSON object")
+	errWrongType          = errors.New("booking payload property has the wrong JSON type")
+
+	errNightsNotPositive      = errors.New("nights should be positive")
+	errSellingRateNotPositive = errors.New("selling_rate should be positive")
+	errMarginNotPositive      = errors.New("margin should be positive")
+	errSellingRateTooHigh     = fmt.Errorf("selling_rate should not be above %d", maxSellingRate)
+	errMarginTooHigh          = fmt.Errorf("margin should not be above %d", maxMargin)
 )
 
 type Booking struct {
-	RequestID   string    `json:"request_id"`
-	CheckIn     time.Time `json:"check_in"`
-	Nights      int32     `json:"nights"`
-	SellingRate int32     `json:"selling_rate"`
-	Margin      int32     `json:"margin"`
+	RequestID   string       `json:"request_id"`
+	CheckIn     time.Time    `json:"check_in"`
+	Nights      int32        `json:"nights"`
+	SellingRate money.Amount `json:"selling_rate"`
+	Margin      int32        `json:"margin"`
+	// Currency is the ISO 4217 code of the selling rate, optional.
+	Currency string `json:"currency,omitempty"`
 }
 
 func (b *Booking) valid() bool {
-	return b.Nights > 0 && b.SellingRate > 0 && b.Margin > 0
+	return b.validate() == nil
+}
+
+// validate returns the first rule the booking breaks to be taken into account, or nil.
+func (b *Booking) validate() error {
+	if issues := b.violations(); len(issues) > 0 {
+		return issues[0].err
+	}
+	return nil
+}
+
+// violations returns every rule the booking breaks to be taken into account, by field.
+func (b *Booking) violations() []fieldIssue {
+	var issues []fieldIssue
+	if b.Nights <= 0 {
+		issues = append(issues, fieldIssue{field: "nights", reason: ReasonNotPositive, err: errNightsNotPositive})
+	}
+	switch {
+	case b.SellingRate <= 0:
+		issues = append(issues, fieldIssue{field: "selling_rate", reason: ReasonNotPositive, err: errSellingRateNotPositive})
+	case b.SellingRate > money.FromUnits(maxSellingRate):
+		issues = append(issues, fieldIssue{field: "selling_rate", reason: ReasonTooHigh, err: errSellingRateTooHigh})
+	}
+	switch {
+	case b.Margin <= 0:
+		issues = append(issues, fieldIssue{field: "margin", reason: ReasonNotPositive, err: errMarginNotPositive})
+	case b.Margin > maxMargin:
+		issues = append(issues, fieldIssue{field: "margin", reason: ReasonTooHigh, err: errMarginTooHigh})
+	}
+	return issues
 }
 
 func (b *Booking) UnmarshalJSON(data []byte) error {
+	if issues := b.decode(data); len(issues) > 0 {
+		return issues[0].err
+	}
+	return nil
+}
+
+// decode sets the booking from the JSON object data, returning every property that is missing or cannot be read, in
+// the order of the fields of the booking.
+func (b *Booking) decode(data []byte) []fieldIssue {
+	// numbers are kept as they came, so decimal amounts are read exactly.
 	payload := make(map[string]interface{})
-	err := json.Unmarshal(data, &payload)
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	err := d.Decode(&payload)
 	if err != nil {
-		return err
+		return []fieldIssue{{reason: ReasonWrongType, err: fmt.Errorf("%w: %v", errBookingType, err)}}
 	}
+	return b.decodePayload(payload)
+}
 
-	if _, ok := payload["request_id"]; !ok {
-		return errRequestIDMissing
+// decodePayload sets the booking from the properties of payload, numbers being json.Number, returning every property
+// that is missing or cannot be read.
+func (b *Booking) decodePayload(payload map[string]interface{}) []fieldIssue {
+	var issues []fieldIssue
+	requestID, issue := stringProperty(payload, "request_id", errRequestIDMissing)
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
-	if _, ok := payload["check_in"]; !ok {
-		return errCheckInMissing
+
+	checkIn, issue := stringProperty(payload, "check_in", errCheckInMissing)
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
-	if _, ok := payload["nights"]; !ok {
-		return errNightsMissing
+	t, err := timeparser.ToTime(checkIn)
+	if issue == nil && err != nil {
+		issues = append(issues, fieldIssue{field: "check_in", reason: ReasonBadDate, err: fmt.Errorf("%w: %v", errCheckInInvalid, err)})
 	}
-	if _, ok := payload["selling_rate"]; !ok {
-		return errSellingRateMissing
+
+	nights, issue := wholeProperty(payload, "nights", errNightsMissing)
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
-	if _, ok := payload["margin"]; !ok {
-		return errMarginMissing
+
+	sellingRate, issue := amountProperty(payload, "selling_rate", errSellingRateMissing, errSellingRateInvalid)
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
 
-	t, err := timeparser.ToTime(payload["check_in"].(string))
-	if err != nil {
-		return err
+	margin, issue := wholeProperty(payload, "margin", errMarginMissing)
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
 
-	b.RequestID = payload["request_id"].(string)
+	var currency string
+	if _, ok := payload["currency"]; ok {
+		currency, issue = stringProperty(payload, "currency", nil)
+		currency = strings.ToUpper(currency)
+		switch {
+		case issue != nil:
+			issues = append(issues, *issue)
+		case !fx.ValidCurrency(currency):
+			issues = append(issues, fieldIssue{field: "currency", reason: ReasonBadCurrency, err: fx.ErrCurrency})
+		}
+	}
+
+	b.RequestID = requestID
 	b.CheckIn = t
-	b.Nights = int32(payload["nights"].(float64))
-	b.SellingRate = int32(payload["selling_rate"].(float64))
-	b.Margin = int32(payload["margin"].(float64))
-	return nil
+	b.Nights = nights
+	b.SellingRate = sellingRate
+	b.Margin = margin
+	b.Currency = currency
+	return issues
+}
+
+// stringProperty returns the string property field of payload, or the issue of it missing or not being a string.
+func stringProperty(payload map[string]interface{}, field string, missing error) (string, *fieldIssue) {
+	v, ok := payload[field]
+	if !ok {
+		return "", &fieldIssue{field: field, reason: ReasonMissing, err: missing}
+	}
+	s, ok := v.(string)
+	if !ok {
+		issue := wrongType(field, "string")
+		return "", &issue
+	}
+	return s, nil
+}
+
+// wholeProperty returns the whole number property field of payload, or the issue of it missing or not being a whole
+// number that fits 32 bits.
+func wholeProperty(payload map[string]interface{}, field string, missing error) (int32, *fieldIssue) {
+	v, ok := payload[field]
+	if !ok {
+		return 0, &fieldIssue{field: field, reason: ReasonMissing, err: missing}
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		issue := wrongType(field, "whole number")
+		return 0, &issue
+	}
+	i, err := n.Int64()
+	if err != nil || i < math.MinInt32 || i > math.MaxInt32 {
+		issue := wrongType(field, "whole number")
+		return 0, &issue
+	}
+	return int32(i), nil
+}
+
+// amountProperty returns the decimal amount property field of payload, either as a number or as a string, or the issue
+// of it missing, having another type or not being an amount in minor units.
+func amountProperty(payload map[string]interface{}, field string, missing, invalid error) (money.Amount, *fieldIssue) {
+	v, ok := payload[field]
+	if !ok {
+		return 0, &fieldIssue{field: field, reason: ReasonMissing, err: missing}
+	}
+
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		issue := wrongType(field, "decimal number")
+		return 0, &issue
+	}
+
+	a, err := money.Parse(s)
+	if err != nil {
+		return 0, &fieldIssue{field: field, reason: ReasonBadAmount, err: fmt.Errorf("%w: %v", invalid, err)}
+	}
+	return a, nil
+}
+
+func wrongType(field, kind string) fieldIssue {
+	return fieldIssue{field: field, reason: ReasonWrongType, err: fmt.Errorf("%w: %s should be a %s", errWrongType, field, kind)}
 }
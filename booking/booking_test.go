@@ -3,6 +3,9 @@
 package booking
 
 import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -11,13 +14,14 @@ func TestValid(t *testing.T) {
 	tests := map[string]struct {
 		booking  Booking
 		expected bool
+		err      error
 	}{
 		"valid": {
 			booking: Booking{
 				RequestID:   "made-up",
 				CheckIn:     time.Time{},
 				Nights:      10,
-				SellingRate: 55,
+				SellingRate: money.FromUnits(55),
 				Margin:      15,
 			},
 			expected: true,
@@ -27,30 +31,65 @@ func TestValid(t *testing.T) {
 				RequestID:   "made-up",
 				CheckIn:     time.Time{},
 				Nights:      0,
-				SellingRate: 13,
+				SellingRate: money.FromUnits(13),
 				Margin:      5,
 			},
 			expected: false,
+			err:      errNightsNotPositive,
 		},
 		"invalid: no selling rate": {
 			booking: Booking{
 				RequestID:   "made-up",
 				CheckIn:     time.Time{},
 				Nights:      10,
-				SellingRate: 0,
+				SellingRate: money.FromUnits(0),
 				Margin:      5,
 			},
 			expected: false,
+			err:      errSellingRateNotPositive,
 		},
 		"invalid: no margin": {
 			booking: Booking{
 				RequestID:   "made-up",
 				CheckIn:     time.Time{},
 				Nights:      10,
-				SellingRate: 13,
+				SellingRate: money.FromUnits(13),
 				Margin:      0,
 			},
 			expected: false,
+			err:      errMarginNotPositive,
+		},
+		"invalid: selling rate too high": {
+			booking: Booking{
+				RequestID:   "made-up",
+				CheckIn:     time.Time{},
+				Nights:      10,
+				SellingRate: money.FromUnits(maxSellingRate) + 1,
+				Margin:      5,
+			},
+			expected: false,
+			err:      errSellingRateTooHigh,
+		},
+		"valid: margin above 100": {
+			booking: Booking{
+				RequestID:   "made-up",
+				CheckIn:     time.Time{},
+				Nights:      10,
+				SellingRate: money.FromUnits(13),
+				Margin:      150,
+			},
+			expected: true,
+		},
+		"invalid: margin too high": {
+			booking: Booking{
+				RequestID:   "made-up",
+				CheckIn:     time.Time{},
+				Nights:      10,
+				SellingRate: money.FromUnits(13),
+				Margin:      maxMargin + 1,
+			},
+			expected: false,
+			err:      errMarginTooHigh,
 		},
 	}
 	for name, tt := range tests {
@@ -61,6 +100,49 @@ func TestValid(t *testing.T) {
 			if got != tt.expected {
 				t.Errorf("got: %t, expected: %t", got, tt.expected)
 			}
+			if err := tt.booking.validate(); err != tt.err {
+				t.Errorf("got: %v, expected: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBooking_UnmarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		payload     string
+		expected    Booking
+		expectedErr error
+	}{
+		"valid": {
+			payload:  `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": "99.90", "margin": 10, "currency": "gbp"}`,
+			expected: Booking{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.MustParse("99.9"), Margin: 10, Currency: "GBP"},
+		},
+		"not an object":           {payload: `1`, expectedErr: errBookingType},
+		"missing request_id":      {payload: `{"check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10}`, expectedErr: errRequestIDMissing},
+		"request_id not a string": {payload: `{"request_id": 1, "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10}`, expectedErr: errWrongType},
+		"check_in not a string":   {payload: `{"request_id": "A", "check_in": 20230101, "nights": 2, "selling_rate": 100, "margin": 10}`, expectedErr: errWrongType},
+		"bad check_in":            {payload: `{"request_id": "A", "check_in": "2023-13-01", "nights": 2, "selling_rate": 100, "margin": 10}`, expectedErr: errCheckInInvalid},
+		"nights not a number":     {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": "2", "selling_rate": 100, "margin": 10}`, expectedErr: errWrongType},
+		"nights not whole":        {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 2.5, "selling_rate": 100, "margin": 10}`, expectedErr: errWrongType},
+		"nights too big":          {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 3000000000, "selling_rate": 100, "margin": 10}`, expectedErr: errWrongType},
+		"selling_rate a boolean":  {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": true, "margin": 10}`, expectedErr: errWrongType},
+		"selling_rate not amount": {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": "ten", "margin": 10}`, expectedErr: errSellingRateInvalid},
+		"margin null":             {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": null}`, expectedErr: errWrongType},
+		"currency not a string":   {payload: `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10, "currency": 978}`, expectedErr: errWrongType},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got Booking
+			err := got.UnmarshalJSON([]byte(tt.payload))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
 		})
 	}
 }
@@ -0,0 +1,153 @@
+package booking
+
+import (
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"time"
+)
+
+const maxCalendarNights = 3660
+
+var errCalendarSpan = fmt.Errorf("calendar should not span more than %d nights", maxCalendarNights)
+
+// CalendarNight holds the bookings staying on a night, and the selling rate and profit of those bookings allocated
+// evenly to each of their nights, the cents left over going to their first nights so nothing is lost.
+type CalendarNight struct {
+	Date     string       `json:"date"`
+	Bookings int          `json:"bookings"`
+	Revenue  money.Amount `json:"revenue"`
+	Profit   money.Amount `json:"profit"`
+}
+
+// Calendar returns every night from the first check-in to the last check-out of the valid bookings, under the given
+// options, and what the bookings staying on each of them bring.
+func (s *StatsService) Calendar(bookings []Booking, opts ...StatsOption) ([]CalendarNight, error) {
+	options, err := s.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer logOperation(options.loggerOrDefault(), "calendar", time.Now())
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	within := make([]Booking, 0, len(bookings))
+	for _, booking := range bookings {
+		booking, ok := options.horizon.clip(booking)
+		if !ok || !booking.valid() {
+			continue
+		}
+		booking, err = options.conversion.convert(booking)
+		if err != nil {
+			return nil, err
+		}
+		within = append(within, booking)
+	}
+	return calendar(within)
+}
+
+// Calendar returns the calendar of the best combination of bookings, under the same rules as MaximTotalProfits, so
+// the occupancy of every night is the number of rooms sold.
+func (m *MaximizeService) Calendar(bookings []Booking, opts ...MaximizeOption) ([]CalendarNight, error) {
+	options, err := m.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer logOperation(options.loggerOrDefault(), "maximize_calendar", time.Now())
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := m.problem(bookings, options)
+	if err != nil {
+		return nil, err
+	}
+
+	best, err := p.best()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]Booking, 0, len(best))
+	for _, i := range best {
+		selected = append(selected, p.stays[i].booking)
+	}
+	return calendar(selected)
+}
+
+// calendar expands the valid bookings into their nights. Every booking only marks where its first night starts and
+// its last night ends, so running sums over the nights give the calendar in time linear with the bookings plus the
+// nights.
+func calendar(bookings []Booking) ([]CalendarNight, error) {
+	if len(bookings) == 0 {
+		return []CalendarNight{}, nil
+	}
+
+	first, last := dayNumber(bookings[0].CheckIn), int64(0)
+	for _, b := range bookings {
+		start := dayNumber(b.CheckIn)
+		if start < first {
+			first = start
+		}
+		if end := start + int64(b.Nights); end > last {
+			last = end
+		}
+	}
+	if last-first > maxCalendarNights {
+		return nil, errCalendarSpan
+	}
+
+	var (
+		span    = last - first
+		counts  = make([]int, span+1)
+		revenue = make([]money.Amount, span+1)
+		profits = make([]money.Amount, span+1)
+	)
+	for _, b := range bookings {
+		var (
+			start = dayNumber(b.CheckIn) - first
+			end   = start + int64(b.Nights)
+		)
+		counts[start]++
+		counts[end]--
+		allocate(revenue, start, end, b.SellingRate)
+		allocate(profits, start, end, profit(b.SellingRate, b.Margin))
+	}
+
+	var (
+		nights        = make([]CalendarNight, 0, span)
+		staying       int
+		nightRevenue  money.Amount
+		nightProfit   money.Amount
+		secondsPerDay = int64(24 * 60 * 60)
+	)
+	for day := int64(0); day < span; day++ {
+		staying += counts[day]
+		nightRevenue += revenue[day]
+		nightProfit += profits[day]
+
+		nights = append(nights, CalendarNight{
+			Date:     time.Unix((first+day)*secondsPerDay, 0).UTC().Format("2006-01-02"),
+			Bookings: staying,
+			Revenue:  nightRevenue,
+			Profit:   nightProfit,
+		})
+	}
+	return nights, nil
+}
+
+// allocate marks amount split evenly over the nights from start to end, excluded, on the running sums of diffs. The
+// cents that cannot be split go one by one to the first nights.
+func allocate(diffs []money.Amount, start, end int64, amount money.Amount) {
+	var (
+		nights   = end - start
+		perNight = amount.MulDiv(1, nights, money.Floor)
+		leftover = int64(amount - perNight*money.Amount(nights))
+	)
+	diffs[start] += perNight
+	diffs[end] -= perNight
+	diffs[start] += money.FromMinor(1)
+	diffs[start+leftover] -= money.FromMinor(1)
+}
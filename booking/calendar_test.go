@@ -0,0 +1,130 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      2,
+			SellingRate: money.FromUnits(200),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2023-01-02"),
+			Nights:      3,
+			SellingRate: money.FromUnits(300),
+			Margin:      20,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2023-01-07"),
+			Nights:      1,
+			SellingRate: money.FromUnits(50),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      0,
+			SellingRate: money.FromUnits(50),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		calendar    func() ([]CalendarNight, error)
+		expected    []CalendarNight
+		expectedErr error
+	}{
+		"every booking": {
+			calendar: func() ([]CalendarNight, error) {
+				return newStatsService(t).Calendar(bookings)
+			},
+			expected: []CalendarNight{
+				{Date: "2023-01-01", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+				{Date: "2023-01-02", Bookings: 2, Revenue: money.FromUnits(200), Profit: money.FromUnits(30)},
+				{Date: "2023-01-03", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-04", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-05"},
+				{Date: "2023-01-06"},
+				{Date: "2023-01-07", Bookings: 1, Revenue: money.FromUnits(50), Profit: money.FromUnits(5)},
+			},
+		},
+		"horizon": {
+			calendar: func() ([]CalendarNight, error) {
+				return newStatsService(t).Calendar(bookings, WithStatsHorizon(time.Time{}, parse("2023-01-02"), HorizonProrate))
+			},
+			expected: []CalendarNight{
+				{Date: "2023-01-01", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+				{Date: "2023-01-02", Bookings: 2, Revenue: money.FromUnits(200), Profit: money.FromUnits(30)},
+			},
+		},
+		"best combination": {
+			calendar: func() ([]CalendarNight, error) {
+				return newMaximizeService(t).Calendar(bookings)
+			},
+			expected: []CalendarNight{
+				{Date: "2023-01-02", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-03", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-04", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-05"},
+				{Date: "2023-01-06"},
+				{Date: "2023-01-07", Bookings: 1, Revenue: money.FromUnits(50), Profit: money.FromUnits(5)},
+			},
+		},
+		"best combination with two rooms": {
+			calendar: func() ([]CalendarNight, error) {
+				return newMaximizeService(t).Calendar(bookings, WithRooms(2), WithExcluded("C"))
+			},
+			expected: []CalendarNight{
+				{Date: "2023-01-01", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+				{Date: "2023-01-02", Bookings: 2, Revenue: money.FromUnits(200), Profit: money.FromUnits(30)},
+				{Date: "2023-01-03", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+				{Date: "2023-01-04", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(20)},
+			},
+		},
+		"no valid bookings": {
+			calendar: func() ([]CalendarNight, error) {
+				return newStatsService(t).Calendar(bookings[3:])
+			},
+			expected: []CalendarNight{},
+		},
+		"span too long": {
+			calendar: func() ([]CalendarNight, error) {
+				return newStatsService(t).Calendar(append([]Booking{{
+					RequestID:   "E",
+					CheckIn:     parse("2040-01-01"),
+					Nights:      1,
+					SellingRate: money.FromUnits(50),
+					Margin:      10,
+				}}, bookings...))
+			},
+			expectedErr: errCalendarSpan,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.calendar()
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package booking
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	csvContentType = "text/csv"
+
+	defaultDelimiter = ','
+)
+
+var (
+	errCSVHeader = errors.New("csv header should have request_id, check_in, nights, selling_rate and margin columns")
+	errCSV       = errors.New("invalid csv")
+	errDelimiter = errors.New("delimiter should be a single character other than a quote or a line break")
+)
+
+var (
+	// csvColumns are the columns every CSV payload should have, and csvOptionalColumns the ones it may have.
+	csvColumns         = []string{"request_id", "check_in", "nights", "selling_rate", "margin"}
+	csvOptionalColumns = []string{"currency"}
+	// csvNumbers are the columns read as JSON numbers, the others being strings.
+	csvNumbers = map[string]bool{"nights": true, "selling_rate": true, "margin": true}
+)
+
+// newDelimiter returns the delimiter s of a CSV payload, a comma when empty.
+func newDelimiter(s string) (rune, error) {
+	if s == "" {
+		return defaultDelimiter, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("%w: %q", errDelimiter, s)
+	}
+	return r, nil
+}
+
+// decodeCSV returns the bookings of the rows of the CSV payload r under mode, with the same rules as the JSON ones,
+// and how many were left out as they could not be read. The header row names the columns, in any order, and columns
+// other than the booking properties are ignored. Empty cells are taken as missing properties, and issues are reported
+// at the index of the row after the header.
+func decodeCSV(r io.Reader, delimiter rune, mode ValidationMode) ([]Booking, int, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, 0, errInvalidRequestBody
+	}
+	if err != nil {
+		return nil, 0, csvError(err)
+	}
+	columns, err := csvHeader(header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c := newCollector(mode, 0)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, csvError(err)
+		}
+		if i == maxBookings {
+			return nil, 0, errTooManyBookings
+		}
+
+		payload := make(map[string]interface{}, len(columns))
+		for name, column := range columns {
+			v := strings.TrimSpace(record[column])
+			switch {
+			case v == "":
+			case csvNumbers[name]:
+				payload[name] = json.Number(v)
+			default:
+				payload[name] = v
+			}
+		}
+
+		var b Booking
+		found := b.decodePayload(payload)
+		err = c.add(i, b, found)
+		if err != nil {
+			return nil, 0, fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	return c.result()
+}
+
+// csvError returns the error answering err, reading a CSV payload: the payloads that cannot be parsed are invalid,
+// while other errors come from reading the body.
+func csvError(err error) error {
+	var parse *csv.ParseError
+	if errors.As(err, &parse) {
+		return fmt.Errorf("%w: %v", errCSV, err)
+	}
+	return readError(err)
+}
+
+// csvHeader returns the index of every booking column of header, failing when any required one is missing.
+func csvHeader(header []string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			// a byte order mark is left at the start of the header by some spreadsheets.
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	var (
+		columns = make(map[string]int, len(csvColumns)+len(csvOptionalColumns))
+		missing []string
+	)
+	for _, name := range csvColumns {
+		i, ok := index[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		columns[name] = i
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing %s", errCSVHeader, strings.Join(missing, ", "))
+	}
+
+	for _, name := range csvOptionalColumns {
+		if i, ok := index[name]; ok {
+			columns[name] = i
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,143 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	tests := map[string]struct {
+		payload        string
+		delimiter      rune
+		mode           ValidationMode
+		expected       []Booking
+		expectedUnread int
+		expectedIssues []Issue
+		expectedErr    error
+	}{
+		"valid": {
+			payload: "request_id,check_in,nights,selling_rate,margin\n" +
+				"A,2023-01-01,2,199.99,10\n" +
+				"B,2023-01-05,1,50,20\n",
+			delimiter: ',',
+			expected: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.MustParse("199.99"), Margin: 10},
+				{RequestID: "B", CheckIn: parse("2023-01-05"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 20},
+			},
+		},
+		"columns in any order, extra ones ignored": {
+			payload: "\ufeffMargin; partner ;selling_rate;currency;check_in;nights;request_id\n" +
+				"10;acme;\"1.5\";gbp;2023-01-01;2;A\n" +
+				"10;acme;1.5;;2023-01-01;2;B\n",
+			delimiter: ';',
+			expected: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.MustParse("1.5"), Margin: 10, Currency: "GBP"},
+				{RequestID: "B", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.MustParse("1.5"), Margin: 10},
+			},
+		},
+		"missing columns": {
+			payload:     "request_id,check_in,nights\nA,2023-01-01,2\n",
+			delimiter:   ',',
+			expectedErr: errCSVHeader,
+		},
+		"empty payload": {
+			delimiter:   ',',
+			expectedErr: errInvalidRequestBody,
+		},
+		"rows of another length": {
+			payload:     "request_id,check_in,nights,selling_rate,margin\nA,2023-01-01,2,100\n",
+			delimiter:   ',',
+			expectedErr: errCSV,
+		},
+		"first issue by default": {
+			payload:     "request_id,check_in,nights,selling_rate,margin\nA,2023-01-01,2,100,\n",
+			delimiter:   ',',
+			expectedErr: errMarginMissing,
+		},
+		"lenient": {
+			payload: "request_id,check_in,nights,selling_rate,margin\n" +
+				"A,2023-01-01,two,100,10\n" +
+				"B,2023-01-05,1,50,20\n",
+			delimiter: ',',
+			mode:      ValidationLenient,
+			expected: []Booking{
+				{RequestID: "B", CheckIn: parse("2023-01-05"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 20},
+			},
+			expectedUnread: 1,
+		},
+		"strict": {
+			payload: "request_id,check_in,nights,selling_rate,margin\n" +
+				"A,2023-01-01,two,100,10\n" +
+				"B,01/05/2023,1,-50,20\n",
+			delimiter: ',',
+			mode:      ValidationStrict,
+			expectedIssues: []Issue{
+				{Index: 0, Field: "nights", Reason: ReasonWrongType},
+				{Index: 1, Field: "check_in", Reason: ReasonBadDate},
+				{Index: 1, Field: "selling_rate", Reason: ReasonNotPositive},
+			},
+			expectedErr: errInvalidBookings,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, unread, err := decodeCSV(strings.NewReader(tt.payload), tt.delimiter, tt.mode)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+
+			var validation *ValidationError
+			if errors.As(err, &validation) {
+				for i := range validation.Issues {
+					validation.Issues[i].Message = ""
+				}
+				if !reflect.DeepEqual(validation.Issues, tt.expectedIssues) {
+					t.Errorf("got issues: %+v, expected: %+v", validation.Issues, tt.expectedIssues)
+				}
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+			if unread != tt.expectedUnread {
+				t.Errorf("got %d unread, expected %d", unread, tt.expectedUnread)
+			}
+		})
+	}
+}
+
+func TestNewDelimiter(t *testing.T) {
+	tests := map[string]struct {
+		delimiter   string
+		expected    rune
+		expectedErr error
+	}{
+		"comma by default": {expected: ','},
+		"semicolon":        {delimiter: ";", expected: ';'},
+		"tab":              {delimiter: "\t", expected: '\t'},
+		"several":          {delimiter: ";;", expectedErr: errDelimiter},
+		"quote":            {delimiter: `"`, expectedErr: errDelimiter},
+		"line break":       {delimiter: "\n", expectedErr: errDelimiter},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newDelimiter(tt.delimiter)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("got: %q, expected: %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
+)
+
+// conversionRounding rounds the converted selling rates half to even, so rounding errors cancel out across bookings.
+const conversionRounding = money.HalfEven
+
+var (
+	errNoRates              = errors.New("currency conversion is not available without exchange rates")
+	errConvertedRateTooHigh = fmt.Errorf("selling_rate should not be above %d once converted", maxSellingRate)
+)
+
+// Reporting tells the currency the amounts are reported in and the version of the exchange rates used to convert
+// them. When the bookings were taken as they came the version is empty, and so is the currency unless they gave one.
+type Reporting struct {
+	Currency     string `json:"currency,omitempty"`
+	RatesVersion string `json:"rates_version,omitempty"`
+}
+
+// conversion normalises the bookings to a reporting currency with a single rate table. Without rates it leaves the
+// bookings as they come, which only adds up as long as they share their currency, and so does a nil conversion.
+type conversion struct {
+	table    fx.Table
+	currency string
+	// rated tells whether table holds the rates, or currency is the one shared by the bookings taken as they came.
+	rated bool
+}
+
+// newConversion returns the conversion to currency, or to the base currency of the rates when empty, with the current
+// rates of provider. With no provider no currency can be requested, and the bookings are kept in their own one.
+func newConversion(provider fx.RateProvider, currency string) (*conversion, error) {
+	if provider == nil {
+		if currency != "" {
+			return nil, errNoRates
+		}
+		return &conversion{}, nil
+	}
+
+	table, err := provider.Rates()
+	if err != nil {
+		return nil, err
+	}
+
+	if currency == "" {
+		currency = table.Base()
+	}
+	if !table.Has(currency) {
+		return nil, fmt.Errorf("%w: %s", fx.ErrUnknownCurrency, currency)
+	}
+	return &conversion{table: table, currency: currency, rated: true}, nil
+}
+
+// convert returns b with its selling rate in the reporting currency. Bookings with no currency are taken to be in the
+// base currency of the rates, or in the one of the others when there are no rates. The converted rate is held to the
+// same bound as the rates sent, as the amounts are only known to fit in the reporting currency.
+func (c *conversion) convert(b Booking) (Booking, error) {
+	if c == nil {
+		return b, nil
+	}
+	if !c.rated {
+		return c.keep(b)
+	}
+
+	from := b.Currency
+	if from == "" {
+		from = c.table.Base()
+	}
+	if !c.table.Has(from) {
+		return Booking{}, fmt.Errorf("%w: %s of %s", fx.ErrUnknownCurrency, from, b.RequestID)
+	}
+
+	rate, err := c.table.Convert(b.SellingRate, from, c.currency, conversionRounding)
+	if err != nil {
+		return Booking{}, fmt.Errorf("converting %s: %w", b.RequestID, err)
+	}
+	if rate > money.FromUnits(maxSellingRate) {
+		return Booking{}, fmt.Errorf("%w: %s %s of %s", errConvertedRateTooHigh, rate, c.currency, b.RequestID)
+	}
+	b.SellingRate = rate
+	b.Currency = c.currency
+	return b, nil
+}
+
+// keep returns b as it came, once checked it is in the currency of the bookings kept before, as there are no rates to
+// add up amounts in different ones.
+func (c *conversion) keep(b Booking) (Booking, error) {
+	switch {
+	case b.Currency == "" || b.Currency == c.currency:
+	case c.currency == "":
+		c.currency = b.Currency
+	default:
+		return Booking{}, fmt.Errorf("%w: %s of %s after %s", errNoRates, b.Currency, b.RequestID, c.currency)
+	}
+	return b, nil
+}
+
+func (c *conversion) reporting() Reporting {
+	if c == nil {
+		return Reporting{}
+	}
+	if !c.rated {
+		return Reporting{Currency: c.currency}
+	}
+	return Reporting{Currency: c.currency, RatesVersion: c.table.Version()}
+}
@@ -0,0 +1,212 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+)
+
+func testRates(t testing.TB) fx.RateProvider {
+	t.Helper()
+	table, err := fx.NewTable("2024-06-01", "EUR", map[string]string{"GBP": "0.8", "USD": "1.25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fx.NewStaticRates(table)
+}
+
+func TestConversion_Convert(t *testing.T) {
+	b := Booking{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10}
+
+	tests := map[string]struct {
+		rates       fx.RateProvider
+		currency    string
+		booking     Booking
+		expected    Booking
+		expectedErr error
+	}{
+		"no rates": {
+			booking:  b,
+			expected: b,
+		},
+		"no rates with a currency": {
+			currency:    "GBP",
+			booking:     b,
+			expectedErr: errNoRates,
+		},
+		"no booking currency is the base one": {
+			rates:    testRates(t),
+			currency: "GBP",
+			booking:  b,
+			expected: Booking{RequestID: "A", CheckIn: b.CheckIn, Nights: 2, SellingRate: money.FromUnits(80), Margin: 10, Currency: "GBP"},
+		},
+		"to the base currency by default": {
+			rates:    testRates(t),
+			booking:  Booking{RequestID: "A", CheckIn: b.CheckIn, Nights: 2, SellingRate: money.FromUnits(100), Margin: 10, Currency: "USD"},
+			expected: Booking{RequestID: "A", CheckIn: b.CheckIn, Nights: 2, SellingRate: money.FromUnits(80), Margin: 10, Currency: "EUR"},
+		},
+		"across the base currency": {
+			rates:    testRates(t),
+			currency: "USD",
+			booking:  Booking{RequestID: "A", CheckIn: b.CheckIn, Nights: 2, SellingRate: money.MustParse("99.99"), Margin: 10, Currency: "GBP"},
+			expected: Booking{RequestID: "A", CheckIn: b.CheckIn, Nights: 2, SellingRate: money.MustParse("156.23"), Margin: 10, Currency: "USD"},
+		},
+		"unknown reporting currency": {
+			rates:       testRates(t),
+			currency:    "JPY",
+			booking:     b,
+			expectedErr: fx.ErrUnknownCurrency,
+		},
+		"unknown booking currency": {
+			rates:       testRates(t),
+			booking:     Booking{RequestID: "A", Nights: 2, SellingRate: money.FromUnits(100), Margin: 10, Currency: "JPY"},
+			expectedErr: fx.ErrUnknownCurrency,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, err := newConversion(tt.rates, tt.currency)
+			if err == nil {
+				var got Booking
+				got, err = c.convert(tt.booking)
+				if err == nil && !reflect.DeepEqual(got, tt.expected) {
+					t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+				}
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestConversion_WithoutRates(t *testing.T) {
+	tests := map[string]struct {
+		currencies  []string
+		expected    Reporting
+		expectedErr error
+	}{
+		"no currency":                 {currencies: []string{"", ""}},
+		"a single currency":           {currencies: []string{"GBP", "", "GBP"}, expected: Reporting{Currency: "GBP"}},
+		"different currencies":        {currencies: []string{"GBP", "USD"}, expectedErr: errNoRates},
+		"different after no currency": {currencies: []string{"", "USD", "GBP"}, expectedErr: errNoRates},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, err := newConversion(nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, currency := range tt.currencies {
+				b := Booking{RequestID: string(rune('A' + i)), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10, Currency: currency}
+				var got Booking
+				got, err = c.convert(b)
+				if err != nil {
+					break
+				}
+				if got != b {
+					t.Errorf("got: %+v, expected: %+v", got, b)
+				}
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if err == nil && c.reporting() != tt.expected {
+				t.Errorf("got: %+v, expected: %+v", c.reporting(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatsService_SummarizeWithCurrency(t *testing.T) {
+	service, err := NewStatsService(WithStatsRates(testRates(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bookings := []Booking{
+		{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10},
+		{RequestID: "B", CheckIn: parse("2023-01-01"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10, Currency: "GBP"},
+		{RequestID: "C", CheckIn: parse("2023-01-01"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10, Currency: "USD"},
+	}
+
+	tests := map[string]struct {
+		opts        []StatsOption
+		expected    Summary
+		expectedErr error
+	}{
+		"base currency": {
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("10.17"), MinNight: money.FromUnits(8), MaxNight: money.MustParse("12.5")},
+				Reporting:      Reporting{Currency: "EUR", RatesVersion: "2024-06-01"},
+			},
+		},
+		"reporting currency": {
+			opts: []StatsOption{WithStatsCurrency("GBP")},
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("8.13"), MinNight: money.MustParse("6.4"), MaxNight: money.FromUnits(10)},
+				Reporting:      Reporting{Currency: "GBP", RatesVersion: "2024-06-01"},
+			},
+		},
+		"invalid currency": {
+			opts:        []StatsOption{WithStatsCurrency("pounds")},
+			expectedErr: fx.ErrCurrency,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := service.Summarize(bookings, tt.opts...)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsWithCurrency(t *testing.T) {
+	service := newMaximizeService(t, WithRates(testRates(t)))
+
+	// A is worth more in its own currency, but less than B once both are in euros.
+	bookings := []Booking{
+		{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.FromUnits(110), Margin: 10, Currency: "USD"},
+		{RequestID: "B", CheckIn: parse("2023-01-02"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10},
+	}
+
+	got, err := service.MaximTotalProfits(bookings, WithCurrency("GBP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := MaximizeProfit{
+		RequestIDS:     []string{"B"},
+		TotalProfit:    money.FromUnits(8),
+		ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(4), MinNight: money.FromUnits(4), MaxNight: money.FromUnits(4)},
+		Objective:      ObjectiveProfit,
+		ObjectiveValue: money.FromUnits(8),
+		Reporting:      Reporting{Currency: "GBP", RatesVersion: "2024-06-01"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got: %+v, expected: %+v", got, expected)
+	}
+
+	_, err = newMaximizeService(t).MaximTotalProfits(bookings, WithCurrency("GBP"))
+	if !errors.Is(err, errNoRates) {
+		t.Errorf("got error: %v, expected: %v", err, errNoRates)
+	}
+}
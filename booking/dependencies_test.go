@@ -0,0 +1,51 @@
+//go:build unit
+
+package booking
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// testWriter writes the lines logged by a test to its log, shown when it fails or runs verbose.
+type testWriter struct {
+	tb testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func testLogger(tb testing.TB) *slog.Logger {
+	return slog.New(slog.NewTextHandler(testWriter{tb: tb}, nil))
+}
+
+func newStatsService(tb testing.TB, opts ...StatsOption) *StatsService {
+	tb.Helper()
+	s, err := NewStatsService(append([]StatsOption{WithStatsLogger(testLogger(tb))}, opts...)...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+func newMaximizeService(tb testing.TB, opts ...MaximizeOption) *MaximizeService {
+	tb.Helper()
+	s, err := NewMaximizeService(newStatsService(tb), append([]MaximizeOption{WithLogger(testLogger(tb))}, opts...)...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+func newHandler(tb testing.TB, opts ...HandlerOption) *Handler {
+	tb.Helper()
+	h, err := NewHandler(newStatsService(tb), newMaximizeService(tb), append([]HandlerOption{WithHandlerLogger(testLogger(tb))}, opts...)...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return h
+}
@@ -0,0 +1,105 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy tells which booking to take into account when several share a request ID.
+type DuplicatePolicy string
+
+const (
+	// DuplicatesReject fails with the request IDs found more than once.
+	DuplicatesReject DuplicatePolicy = "reject"
+	// DuplicatesKeepFirst keeps the first booking of every request ID.
+	DuplicatesKeepFirst DuplicatePolicy = "first"
+	// DuplicatesKeepLast keeps the last booking of every request ID.
+	DuplicatesKeepLast DuplicatePolicy = "last"
+	// DuplicatesKeepMostProfitable keeps the valid booking of every request ID with the highest profit in the
+	// reporting currency, the first one on ties.
+	DuplicatesKeepMostProfitable DuplicatePolicy = "most_profitable"
+)
+
+var (
+	errDuplicatePolicy    = errors.New("duplicates should be reject, first, last or most_profitable")
+	errDuplicateRequestID = errors.New("request IDs should be unique")
+)
+
+func (p DuplicatePolicy) valid() bool {
+	switch p {
+	case DuplicatesReject, DuplicatesKeepFirst, DuplicatesKeepLast, DuplicatesKeepMostProfitable:
+		return true
+	}
+	return false
+}
+
+// apply returns a single booking per request ID under the policy, in the order they came. An empty policy leaves the
+// bookings as they come. The profits of the bookings are compared once converted with c.
+func (p DuplicatePolicy) apply(bookings []Booking, c *conversion) ([]Booking, error) {
+	if p == "" {
+		return bookings, nil
+	}
+
+	var (
+		kept       = make(map[string]int, len(bookings))
+		duplicates = make(map[string]struct{})
+	)
+	for i, b := range bookings {
+		j, ok := kept[b.RequestID]
+		if !ok {
+			kept[b.RequestID] = i
+			continue
+		}
+		duplicates[b.RequestID] = struct{}{}
+
+		switch p {
+		case DuplicatesKeepLast:
+			kept[b.RequestID] = i
+		case DuplicatesKeepMostProfitable:
+			better, err := moreProfitable(b, bookings[j], c)
+			if err != nil {
+				return nil, err
+			}
+			if better {
+				kept[b.RequestID] = i
+			}
+		}
+	}
+
+	if len(duplicates) == 0 {
+		return bookings, nil
+	}
+	if p == DuplicatesReject {
+		return nil, fmt.Errorf("%w: %s", errDuplicateRequestID, strings.Join(sortedKeys(duplicates), ", "))
+	}
+
+	unique := make([]Booking, 0, len(kept))
+	for i, b := range bookings {
+		if kept[b.RequestID] == i {
+			unique = append(unique, b)
+		}
+	}
+	return unique, nil
+}
+
+// moreProfitable tells whether a is worth more than b: valid bookings beat invalid ones, then the highest profit once
+// converted with c wins.
+func moreProfitable(a, b Booking, c *conversion) (bool, error) {
+	if a.valid() != b.valid() {
+		return a.valid(), nil
+	}
+	if !a.valid() {
+		return false, nil
+	}
+
+	a, err := c.convert(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = c.convert(b)
+	if err != nil {
+		return false, err
+	}
+	return profit(a.SellingRate, a.Margin) > profit(b.SellingRate, b.Margin), nil
+}
@@ -0,0 +1,115 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+)
+
+func TestDuplicatePolicy_Apply(t *testing.T) {
+	var (
+		a1 = Booking{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10}
+		b  = Booking{RequestID: "B", CheckIn: parse("2023-01-02"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 10}
+		a2 = Booking{RequestID: "A", CheckIn: parse("2023-01-03"), Nights: 2, SellingRate: money.FromUnits(120), Margin: 10}
+		a3 = Booking{RequestID: "A", CheckIn: parse("2023-01-05"), Nights: 0, SellingRate: money.FromUnits(900), Margin: 10}
+		c  = Booking{RequestID: "C", CheckIn: parse("2023-01-06"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 10}
+		c2 = Booking{RequestID: "C", CheckIn: parse("2023-01-07"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 10, Currency: "GBP"}
+
+		bookings = []Booking{a1, b, a2, a3, c, c2}
+	)
+
+	tests := map[string]struct {
+		policy      DuplicatePolicy
+		rates       bool
+		bookings    []Booking
+		expected    []Booking
+		expectedErr error
+	}{
+		"as they come": {
+			bookings: bookings,
+			expected: bookings,
+		},
+		"no duplicates": {
+			policy:   DuplicatesReject,
+			bookings: []Booking{a1, b, c},
+			expected: []Booking{a1, b, c},
+		},
+		"reject": {
+			policy:      DuplicatesReject,
+			bookings:    bookings,
+			expectedErr: errDuplicateRequestID,
+		},
+		"keep first": {
+			policy:   DuplicatesKeepFirst,
+			bookings: bookings,
+			expected: []Booking{a1, b, c},
+		},
+		"keep last": {
+			policy:   DuplicatesKeepLast,
+			bookings: bookings,
+			expected: []Booking{b, a3, c2},
+		},
+		"keep the most profitable valid one, the first on ties": {
+			policy:   DuplicatesKeepMostProfitable,
+			bookings: bookings,
+			expected: []Booking{b, a2, c},
+		},
+		"keep the most profitable in the reporting currency": {
+			policy:   DuplicatesKeepMostProfitable,
+			rates:    true,
+			bookings: []Booking{c, c2},
+			expected: []Booking{c2},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var conversion *conversion
+			if tt.rates {
+				var err error
+				conversion, err = newConversion(testRates(t), "")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := tt.policy.apply(tt.bookings, conversion)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsWithDuplicates(t *testing.T) {
+	bookings := []Booking{
+		{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10},
+		{RequestID: "A", CheckIn: parse("2023-01-05"), Nights: 2, SellingRate: money.FromUnits(300), Margin: 10},
+	}
+
+	got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithDuplicates(DuplicatesKeepMostProfitable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.RequestIDS, []string{"A"}) || got.TotalProfit != money.FromUnits(30) {
+		t.Errorf("got: %+v, expected A once with a total profit of 30", got)
+	}
+
+	_, err = newMaximizeService(t).MaximTotalProfits(bookings, WithDuplicates(DuplicatesReject))
+	if !errors.Is(err, errDuplicateRequestID) {
+		t.Errorf("got error: %v, expected: %v", err, errDuplicateRequestID)
+	}
+
+	_, err = newStatsService(t).Summarize(bookings, WithStatsDuplicates("merge"))
+	if !errors.Is(err, errDuplicatePolicy) {
+		t.Errorf("got error: %v, expected: %v", err, errDuplicatePolicy)
+	}
+}
@@ -0,0 +1,191 @@
+package booking
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNotAcceptable = errors.New("no acceptable media type for the response")
+	errFormat        = errors.New("format should be ics")
+)
+
+// formats maps the values of the format query param to the media type they stand for, taking over the Accept header
+// for the clients that cannot set it, like calendar subscriptions.
+var formats = map[string]string{
+	"ics": icsMediaType,
+}
+
+// Encoder writes response bodies in a media type. Handlers pick one for every response by the Accept header of the
+// request.
+type Encoder interface {
+	// MediaType returns the media type of the bodies written, like application/json.
+	MediaType() string
+	// Supports tells whether v can be written.
+	Supports(v any) bool
+	// Encode writes v to w.
+	Encode(w io.Writer, v any) error
+}
+
+// defaultEncoders returns the encoders every handler starts with, JSON first so it answers the requests with no
+// preference.
+func defaultEncoders() []Encoder {
+	return []Encoder{jsonEncoder{}, csvEncoder{}, xmlEncoder{}, msgpackEncoder{}, icsEncoder{now: time.Now}}
+}
+
+// mediaRange is a media range of an Accept header, like text/* or application/json, with its quality.
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the encoder among encoders supporting v of the media type preferred by accept, the value of an
+// Accept header. The most specific range matching a media type gives its quality, and the encoders registered first
+// win on ties.
+func negotiate(encoders []Encoder, accept string, v any) (Encoder, error) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		ranges = []mediaRange{{mediaType: "*/*", q: 1}}
+	}
+
+	var (
+		best  Encoder
+		bestQ float64
+	)
+	for _, e := range encoders {
+		if !e.Supports(v) {
+			continue
+		}
+		if q := quality(ranges, e.MediaType()); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: %s", errNotAcceptable, accept)
+	}
+	return best, nil
+}
+
+// parseAccept returns the media ranges of accept, skipping the ones that cannot be parsed.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific of ranges matching mediaType, 0 when none does.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	var (
+		q           float64
+		specificity = -1
+	)
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(r.mediaType, "*")):
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) MediaType() string {
+	return "application/json"
+}
+
+func (jsonEncoder) Supports(any) bool {
+	return true
+}
+
+func (jsonEncoder) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// node is a JSON value keeping the keys of its objects in order, so the encoders built on it write the fields of a
+// response as the JSON one does. Kind is '{' for objects, '[' for arrays and 0 for scalars, whose value is nil, a
+// bool, a string or a json.Number.
+type node struct {
+	kind     byte
+	keys     []string
+	children []node
+	value    any
+}
+
+// toNode returns the JSON value of v.
+func toNode(v any) (node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return node{}, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return readNode(d)
+}
+
+func readNode(d *json.Decoder) (node, error) {
+	t, err := d.Token()
+	if err != nil {
+		return node{}, err
+	}
+
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return node{value: t}, nil
+	}
+
+	n := node{kind: byte(delim)}
+	for d.More() {
+		if n.kind == '{' {
+			key, err := d.Token()
+			if err != nil {
+				return node{}, err
+			}
+			n.keys = append(n.keys, fmt.Sprint(key))
+		}
+		child, err := readNode(d)
+		if err != nil {
+			return node{}, err
+		}
+		n.children = append(n.children, child)
+	}
+
+	// the closing delimiter.
+	_, err = d.Token()
+	return n, err
+}
@@ -0,0 +1,173 @@
+package booking
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// csvResponseHeader names the columns of the CSV responses, which rows of different types fill in part. Booking rows
+// fill the booking ones and their profit, summary rows the profit of the combination, if any, the profit per night
+// stats and their distribution when requested, and group rows the stats of a group. The rank tells apart the
+// combinations of a ranking. The columns past csvBaseColumns are only written when a row fills them.
+var csvResponseHeader = []string{
+	"type", "request_id", "check_in", "nights", "selling_rate", "margin", "profit",
+	"avg_night", "min_night", "max_night", "currency",
+	"rank", "group_by", "key", "bookings",
+	"median_night", "p90_night", "p95_night", "p99_night", "stddev_night", "valid", "skipped",
+}
+
+// csvBaseColumns is how many columns of csvResponseHeader every CSV response has.
+const csvBaseColumns = 11
+
+// csvResponseColumns is the position of every column of csvResponseHeader.
+var csvResponseColumns = func() map[string]int {
+	columns := make(map[string]int, len(csvResponseHeader))
+	for i, name := range csvResponseHeader {
+		columns[name] = i
+	}
+	return columns
+}()
+
+// csvRow is a row of a CSV response, with the columns its type does not fill left empty.
+type csvRow []string
+
+func newCSVRow(kind string) csvRow {
+	r := make(csvRow, len(csvResponseHeader))
+	r[0] = kind
+	return r
+}
+
+func (r csvRow) set(column, value string) csvRow {
+	r[csvResponseColumns[column]] = value
+	return r
+}
+
+func (r csvRow) setProfitPerNight(p ProfitPerNight) csvRow {
+	return r.set("avg_night", p.AvgNight.String()).set("min_night", p.MinNight.String()).set("max_night", p.MaxNight.String())
+}
+
+// csvMarshaler is implemented by the responses that can be written as CSV, a record per row, the header first.
+type csvMarshaler interface {
+	csvRecords() [][]string
+}
+
+type csvEncoder struct{}
+
+func (csvEncoder) MediaType() string {
+	return "text/csv"
+}
+
+func (csvEncoder) Supports(v any) bool {
+	_, ok := v.(csvMarshaler)
+	return ok
+}
+
+func (csvEncoder) Encode(w io.Writer, v any) error {
+	return csv.NewWriter(w).WriteAll(dropEmptyColumns(v.(csvMarshaler).csvRecords()))
+}
+
+// dropEmptyColumns removes from records, the header first, the columns past csvBaseColumns no row fills.
+func dropEmptyColumns(records [][]string) [][]string {
+	keep := make([]bool, len(csvResponseHeader))
+	for i := range keep {
+		keep[i] = i < csvBaseColumns
+	}
+	for _, r := range records[1:] {
+		for i, v := range r {
+			keep[i] = keep[i] || v != ""
+		}
+	}
+
+	for i, r := range records {
+		kept := make([]string, 0, len(r))
+		for j, v := range r {
+			if keep[j] {
+				kept = append(kept, v)
+			}
+		}
+		records[i] = kept
+	}
+	return records
+}
+
+// csvRecords returns the summary row, then a row per group of every dimension, the dimensions by name.
+func (s Summary) csvRecords() [][]string {
+	summary := newCSVRow("summary").setProfitPerNight(s.ProfitPerNight).set("currency", s.Currency)
+	if d := s.Distribution; d != nil {
+		summary.set("median_night", d.MedianNight.String()).
+			set("p90_night", d.P90Night.String()).
+			set("p95_night", d.P95Night.String()).
+			set("p99_night", d.P99Night.String()).
+			set("stddev_night", d.StdDevNight.String()).
+			set("valid", strconv.Itoa(d.Valid)).
+			set("skipped", strconv.Itoa(d.Skipped))
+	}
+	records := [][]string{csvResponseHeader, summary}
+
+	dimensions := make([]GroupBy, 0, len(s.Groups))
+	for d := range s.Groups {
+		dimensions = append(dimensions, d)
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		return dimensions[i] < dimensions[j]
+	})
+	for _, d := range dimensions {
+		for _, g := range s.Groups[d] {
+			records = append(records, newCSVRow("group").
+				set("group_by", string(d)).
+				set("key", g.Key).
+				set("bookings", strconv.Itoa(g.Bookings)).
+				setProfitPerNight(g.ProfitPerNight).
+				set("currency", s.Currency))
+		}
+	}
+	return records
+}
+
+// selection is a combination of bookings along with the bookings it selects, as the solver took them, so it can also
+// be written as a row per booking followed by a summary row. It is written as the combination in any other media
+// type.
+type selection struct {
+	MaximizeProfit
+	bookings []Booking
+}
+
+// csvRecords returns a row per selected booking, then the summary row, all of them in the reporting currency.
+func (s selection) csvRecords() [][]string {
+	return append([][]string{csvResponseHeader}, s.csvRows("")...)
+}
+
+// csvRows returns the rows of the selection, under rank when it is part of a ranking.
+func (s selection) csvRows(rank string) [][]string {
+	rows := make([][]string, 0, len(s.bookings)+1)
+	for _, b := range s.bookings {
+		rows = append(rows, newCSVRow("booking").
+			set("request_id", b.RequestID).
+			set("check_in", b.CheckIn.Format("2006-01-02")).
+			set("nights", strconv.Itoa(int(b.Nights))).
+			set("selling_rate", b.SellingRate.String()).
+			set("margin", strconv.Itoa(int(b.Margin))).
+			set("profit", profit(b.SellingRate, b.Margin).String()).
+			set("currency", b.Currency).
+			set("rank", rank))
+	}
+	return append(rows, newCSVRow("summary").
+		set("profit", s.TotalProfit.String()).
+		setProfitPerNight(s.ProfitPerNight).
+		set("currency", s.Currency).
+		set("rank", rank))
+}
+
+// ranking is the combinations of the top ones, the best first, written as their selections one after the other in
+// CSV, with their rank from 1. It is written as the list of combinations in any other media type.
+type ranking []selection
+
+func (r ranking) csvRecords() [][]string {
+	records := [][]string{csvResponseHeader}
+	for i, s := range r {
+		records = append(records, s.csvRows(strconv.Itoa(i+1))...)
+	}
+	return records
+}
@@ -0,0 +1,98 @@
+package booking
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icsMediaType = "text/calendar"
+	icsProductID = "-//xsolrac87//booking//EN"
+
+	// icsLineOctets is the longest a content line may be before being folded (RFC 5545 3.1), line break excluded.
+	icsLineOctets = 75
+)
+
+// icsEncoder writes the combination of a selection as an iCalendar (RFC 5545), a whole-day event per booking from
+// check-in to check-out, so front desks can follow it in their calendars. Now stamps the events.
+type icsEncoder struct {
+	now func() time.Time
+}
+
+func (icsEncoder) MediaType() string {
+	return icsMediaType
+}
+
+func (icsEncoder) Supports(v any) bool {
+	_, ok := v.(selection)
+	return ok
+}
+
+func (e icsEncoder) Encode(w io.Writer, v any) error {
+	var (
+		s     = v.(selection)
+		stamp = e.now().UTC().Format("20060102T150405Z")
+		b     strings.Builder
+	)
+
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:"+icsProductID)
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	for _, booking := range s.bookings {
+		description := fmt.Sprintf("Profit: %s", profit(booking.SellingRate, booking.Margin))
+		if booking.Currency != "" {
+			description += " " + booking.Currency
+		}
+		description += fmt.Sprintf(", %d nights at %s, %d%% margin", booking.Nights, booking.SellingRate, booking.Margin)
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+escapeICSText(booking.RequestID))
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+booking.CheckIn.Format("20060102"))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+booking.CheckIn.AddDate(0, 0, int(booking.Nights)).Format("20060102"))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(booking.RequestID))
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(description))
+		writeICSLine(&b, "TRANSP:OPAQUE")
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeICSText escapes the backslashes, semicolons, commas and line breaks of a TEXT value (RFC 5545 3.3.11).
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeICSLine writes the content line, folded into lines of up to icsLineOctets octets, every one after the first
+// starting with a space, without splitting a UTF-8 sequence (RFC 5545 3.1).
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of the continuation lines counts towards their octets.
+		limit = icsLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,119 @@
+package booking
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// msgpackEncoder writes responses in MessagePack, with the same keys and values as the JSON ones. Amounts are written
+// as integers when whole and as 64-bit floats otherwise, as JSON clients read them.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) MediaType() string {
+	return "application/msgpack"
+}
+
+func (msgpackEncoder) Supports(any) bool {
+	return true
+}
+
+func (msgpackEncoder) Encode(w io.Writer, v any) error {
+	n, err := toNode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(appendMsgpack(nil, n))
+	return err
+}
+
+func appendMsgpack(b []byte, n node) []byte {
+	switch n.kind {
+	case '{':
+		b = appendMsgpackLength(b, len(n.keys), 0x80, 0xde, 0xdf)
+		for i, key := range n.keys {
+			b = appendMsgpackString(b, key)
+			b = appendMsgpack(b, n.children[i])
+		}
+		return b
+	case '[':
+		b = appendMsgpackLength(b, len(n.children), 0x90, 0xdc, 0xdd)
+		for _, child := range n.children {
+			b = appendMsgpack(b, child)
+		}
+		return b
+	}
+
+	switch v := n.value.(type) {
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case string:
+		return appendMsgpackString(b, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i)
+		}
+		f, _ := v.Float64()
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+	default:
+		return append(b, 0xc0)
+	}
+}
+
+// appendMsgpackLength appends the header of a map or an array of n items: fix holds up to 15 of them in its low bits,
+// and the 16 and 32 bit formats the rest.
+func appendMsgpackLength(b []byte, n int, fix, format16, format32 byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, format16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, format32), uint32(n))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackInt appends i in the smallest integer format holding it.
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i > 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i > 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i > 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(i)))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
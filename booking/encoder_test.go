@@ -0,0 +1,336 @@
+//go:build unit
+
+package booking
+
+import (
+	"bytes"
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	var (
+		summary = Summary{}
+		top     = []MaximizeProfit{}
+	)
+
+	tests := map[string]struct {
+		accept      string
+		v           any
+		expected    string
+		expectedErr error
+	}{
+		"no preference":             {v: summary, expected: "application/json"},
+		"anything":                  {accept: "*/*", v: summary, expected: "application/json"},
+		"exact":                     {accept: "text/csv", v: summary, expected: "text/csv"},
+		"with parameters":           {accept: "application/xml; charset=utf-8", v: summary, expected: "application/xml"},
+		"highest quality":           {accept: "application/json;q=0.5, application/msgpack", v: summary, expected: "application/msgpack"},
+		"most specific range":       {accept: "text/*;q=0.1, text/csv;q=0, */*;q=0.5", v: summary, expected: "application/json"},
+		"type range":                {accept: "text/*", v: summary, expected: "text/csv"},
+		"first registered on ties":  {accept: "application/xml, application/json", v: summary, expected: "application/json"},
+		"unsupported value":         {accept: "text/csv, application/xml;q=0.1", v: top, expected: "application/xml"},
+		"unparsable ranges skipped": {accept: "nonsense, application/xml;q=2, text/csv", v: summary, expected: "text/csv"},
+		"nothing acceptable":        {accept: "image/png", v: summary, expectedErr: errNotAcceptable},
+		"unsupported only":          {accept: "text/csv", v: top, expectedErr: errNotAcceptable},
+		"refused":                   {accept: "application/json;q=0", v: summary, expectedErr: errNotAcceptable},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := negotiate(defaultEncoders(), tt.accept, tt.v)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if err == nil && got.MediaType() != tt.expected {
+				t.Errorf("got: %s, expected: %s", got.MediaType(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	result := MaximizeProfit{
+		RequestIDS:     []string{"A"},
+		TotalProfit:    money.MustParse("12.5"),
+		ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("6.25"), MinNight: money.MustParse("6.25"), MaxNight: money.MustParse("6.25")},
+		Objective:      ObjectiveProfit,
+		ObjectiveValue: money.MustParse("12.5"),
+	}
+	s := selection{
+		MaximizeProfit: result,
+		bookings: []Booking{
+			{RequestID: "A", CheckIn: parse("2023-01-03"), Nights: 2, SellingRate: money.FromUnits(125), Margin: 10, Currency: "EUR"},
+		},
+	}
+
+	tests := map[string]struct {
+		encoder  Encoder
+		v        any
+		expected string
+	}{
+		"csv selection": {
+			encoder: csvEncoder{},
+			v:       s,
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency\n" +
+				"booking,A,2023-01-03,2,125,10,12.5,,,,EUR\n" +
+				"summary,,,,,,12.5,6.25,6.25,6.25,\n",
+		},
+		"csv summary": {
+			encoder: csvEncoder{},
+			v:       Summary{ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(6), MaxNight: money.FromUnits(10)}, Reporting: Reporting{Currency: "GBP"}},
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency\n" +
+				"summary,,,,,,,8,6,10,GBP\n",
+		},
+		"csv summary with distribution and groups": {
+			encoder: csvEncoder{},
+			v: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(6), MaxNight: money.FromUnits(10)},
+				Distribution:   &Distribution{MedianNight: money.FromUnits(8), P90Night: money.FromUnits(10), P95Night: money.FromUnits(10), P99Night: money.FromUnits(10), StdDevNight: money.FromUnits(2), Valid: 2, Skipped: 1},
+				Groups: map[GroupBy][]Group{
+					GroupByWeekday:      {{Key: "Tuesday", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(6), MaxNight: money.FromUnits(10)}}},
+					GroupByLengthOfStay: {{Key: "1", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(6), MinNight: money.FromUnits(6), MaxNight: money.FromUnits(6)}}, {Key: "2-3", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}}},
+				},
+				Reporting: Reporting{Currency: "GBP"},
+			},
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency," +
+				"group_by,key,bookings,median_night,p90_night,p95_night,p99_night,stddev_night,valid,skipped\n" +
+				"summary,,,,,,,8,6,10,GBP,,,,8,10,10,10,2,2,1\n" +
+				"group,,,,,,,6,6,6,GBP,length_of_stay,1,1,,,,,,,\n" +
+				"group,,,,,,,10,10,10,GBP,length_of_stay,2-3,1,,,,,,,\n" +
+				"group,,,,,,,8,6,10,GBP,weekday,Tuesday,2,,,,,,,\n",
+		},
+		"csv ranking": {
+			encoder: csvEncoder{},
+			v: ranking{s, {
+				MaximizeProfit: MaximizeProfit{TotalProfit: money.MustParse("10"), ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+				bookings:       []Booking{{RequestID: "B", CheckIn: parse("2023-01-03"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10, Currency: "EUR"}},
+			}},
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency,rank\n" +
+				"booking,A,2023-01-03,2,125,10,12.5,,,,EUR,1\n" +
+				"summary,,,,,,12.5,6.25,6.25,6.25,,1\n" +
+				"booking,B,2023-01-03,1,100,10,10,,,,EUR,2\n" +
+				"summary,,,,,,10,10,10,10,,2\n",
+		},
+		"xml": {
+			encoder: xmlEncoder{},
+			v:       s,
+			expected: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<response><request_ids><item>A</item></request_ids><total_profit>12.5</total_profit>` +
+				`<avg_night>6.25</avg_night><min_night>6.25</min_night><max_night>6.25</max_night>` +
+				`<objective>profit</objective><objective_value>12.5</objective_value></response>`,
+		},
+		"xml escaping": {
+			encoder:  xmlEncoder{},
+			v:        []string{"<A & B>"},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><item>&lt;A &amp; B&gt;</item></response>`,
+		},
+		"ics": {
+			encoder: icsEncoder{now: func() time.Time { return time.Date(2023, 1, 1, 9, 30, 0, 0, time.UTC) }},
+			v:       s,
+			expected: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//xsolrac87//booking//EN\r\nCALSCALE:GREGORIAN\r\nMETHOD:PUBLISH\r\n" +
+				"BEGIN:VEVENT\r\nUID:A\r\nDTSTAMP:20230101T093000Z\r\n" +
+				"DTSTART;VALUE=DATE:20230103\r\nDTEND;VALUE=DATE:20230105\r\nSUMMARY:A\r\n" +
+				"DESCRIPTION:Profit: 12.5 EUR\\, 2 nights at 125\\, 10% margin\r\nTRANSP:OPAQUE\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+		},
+		"msgpack": {
+			encoder: msgpackEncoder{},
+			v: map[string]any{
+				"a": []any{nil, true, false, -1, 200, -200, 70000, "x"},
+				"b": 1.5,
+			},
+			expected: "\x82" +
+				"\xa1a" + "\x98" + "\xc0\xc3\xc2\xff\xcc\xc8\xd1\xff\x38\xce\x00\x01\x11\x70\xa1x" +
+				"\xa1b" + "\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got bytes.Buffer
+			err := tt.encoder.Encode(&got, tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("got: %q, expected: %q", got.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// textEncoder writes responses as plain text, to plug a new format in.
+type textEncoder struct{}
+
+func (textEncoder) MediaType() string {
+	return "text/plain"
+}
+
+func (textEncoder) Supports(v any) bool {
+	_, ok := v.(selection)
+	return ok
+}
+
+func (textEncoder) Encode(w io.Writer, v any) error {
+	_, err := io.WriteString(w, strings.Join(v.(selection).RequestIDS, " "))
+	return err
+}
+
+func TestHandler_Accept(t *testing.T) {
+	handler := newHandler(t, WithEncoder(textEncoder{}))
+	payload := `[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10},
+		{"request_id": "B", "check_in": "2023-01-02", "nights": 1, "selling_rate": 200, "margin": 10}
+	]`
+
+	tests := map[string]struct {
+		endpoint            string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedPrefix      string
+	}{
+		"stats as json":       {endpoint: "/stats", expectedCode: http.StatusOK, expectedContentType: "application/json", expectedPrefix: `{"avg_night":15`},
+		"stats as csv":        {endpoint: "/stats", accept: "text/csv", expectedCode: http.StatusOK, expectedContentType: "text/csv", expectedPrefix: "type,request_id"},
+		"maximize as xml":     {endpoint: "/maximize", accept: "application/xml", expectedCode: http.StatusOK, expectedContentType: "application/xml", expectedPrefix: "<?xml"},
+		"maximize as msgpack": {endpoint: "/maximize", accept: "application/msgpack", expectedCode: http.StatusOK, expectedContentType: "application/msgpack", expectedPrefix: "\x87\xabrequest_ids\x91\xa1B"},
+		"plugged in format":   {endpoint: "/maximize", accept: "text/plain", expectedCode: http.StatusOK, expectedContentType: "text/plain", expectedPrefix: "B"},
+		"not acceptable":      {endpoint: "/stats", accept: "text/plain", expectedCode: http.StatusNotAcceptable, expectedContentType: problemContentType},
+		"maximize as ics":     {endpoint: "/maximize", accept: "text/calendar", expectedCode: http.StatusOK, expectedContentType: "text/calendar", expectedPrefix: "BEGIN:VCALENDAR\r\n"},
+		"ics format":          {endpoint: "/maximize?format=ics", accept: "application/json", expectedCode: http.StatusOK, expectedContentType: "text/calendar", expectedPrefix: "BEGIN:VCALENDAR\r\n"},
+		"stats as ics":        {endpoint: "/stats?format=ics", expectedCode: http.StatusNotAcceptable, expectedContentType: problemContentType},
+		"unknown format":      {endpoint: "/maximize?format=pdf", expectedCode: http.StatusBadRequest, expectedContentType: problemContentType},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint, bytes.NewBufferString(payload))
+			req.Header.Set("Accept", tt.accept)
+
+			if strings.HasPrefix(tt.endpoint, "/maximize") {
+				handler.HandlerMaximize(wr, req)
+			} else {
+				handler.HandlerStats(wr, req)
+			}
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+			if ct := wr.Header().Get("Content-Type"); ct != tt.expectedContentType {
+				t.Errorf("got content type %s, expected %s", ct, tt.expectedContentType)
+			}
+			if !strings.HasPrefix(wr.Body.String(), tt.expectedPrefix) {
+				t.Errorf("got body %q, expected it to start with %q", wr.Body.String(), tt.expectedPrefix)
+			}
+		})
+	}
+}
+
+func TestHandler_AcceptDuplicates(t *testing.T) {
+	handler := newHandler(t, WithEncoder(icsEncoder{now: func() time.Time { return time.Date(2023, 1, 1, 9, 30, 0, 0, time.UTC) }}))
+	payload := `[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10},
+		{"request_id": "A", "check_in": "2023-01-02", "nights": 1, "selling_rate": 1000, "margin": 10}
+	]`
+
+	tests := map[string]struct {
+		endpoint string
+		accept   string
+		expected string
+	}{
+		"csv keeps the last": {
+			endpoint: "/maximize?duplicates=last",
+			accept:   "text/csv",
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency\n" +
+				"booking,A,2023-01-02,1,1000,10,100,,,,\n" +
+				"summary,,,,,,100,100,100,100,\n",
+		},
+		"csv picks the most profitable": {
+			endpoint: "/maximize",
+			accept:   "text/csv",
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency\n" +
+				"booking,A,2023-01-02,1,1000,10,100,,,,\n" +
+				"summary,,,,,,100,100,100,100,\n",
+		},
+		"csv ranks the top": {
+			endpoint: "/maximize?top=2",
+			accept:   "text/csv",
+			expected: "type,request_id,check_in,nights,selling_rate,margin,profit,avg_night,min_night,max_night,currency,rank\n" +
+				"booking,A,2023-01-02,1,1000,10,100,,,,,1\n" +
+				"summary,,,,,,100,100,100,100,,1\n" +
+				"booking,A,2023-01-01,2,100,10,10,,,,,2\n" +
+				"summary,,,,,,10,5,5,5,,2\n",
+		},
+		"ics keeps the last": {
+			endpoint: "/maximize?duplicates=last&format=ics",
+			expected: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//xsolrac87//booking//EN\r\nCALSCALE:GREGORIAN\r\nMETHOD:PUBLISH\r\n" +
+				"BEGIN:VEVENT\r\nUID:A\r\nDTSTAMP:20230101T093000Z\r\n" +
+				"DTSTART;VALUE=DATE:20230102\r\nDTEND;VALUE=DATE:20230103\r\nSUMMARY:A\r\n" +
+				"DESCRIPTION:Profit: 100\\, 1 nights at 1000\\, 10% margin\r\nTRANSP:OPAQUE\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint, bytes.NewBufferString(payload))
+			req.Header.Set("Accept", tt.accept)
+			handler.HandlerMaximize(wr, req)
+			if wr.Code != http.StatusOK {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, http.StatusOK)
+			}
+			if got := wr.Body.String(); got != tt.expected {
+				t.Errorf("got: %q, expected: %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteICSLine(t *testing.T) {
+	tests := map[string]struct {
+		line     string
+		expected string
+	}{
+		"short":       {line: "UID:A", expected: "UID:A\r\n"},
+		"75 octets":   {line: strings.Repeat("a", 75), expected: strings.Repeat("a", 75) + "\r\n"},
+		"folded":      {line: strings.Repeat("a", 160), expected: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n " + strings.Repeat("a", 11) + "\r\n"},
+		"multi-octet": {line: strings.Repeat("a", 74) + "é", expected: strings.Repeat("a", 74) + "\r\n é\r\n"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var b strings.Builder
+			writeICSLine(&b, tt.line)
+			if b.String() != tt.expected {
+				t.Errorf("got: %q, expected: %q", b.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeICSText(t *testing.T) {
+	got := escapeICSText("a\\b;c,d\ne\r\nf")
+	expected := `a\\b\;c\,d\ne\nf`
+	if got != expected {
+		t.Errorf("got: %q, expected: %q", got, expected)
+	}
+}
@@ -0,0 +1,73 @@
+package booking
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const (
+	// xmlRoot names the root element of every XML response, and xmlItem the elements of the arrays.
+	xmlRoot = "response"
+	xmlItem = "item"
+)
+
+// xmlEncoder writes responses as XML elements named as their JSON keys, in the same order.
+type xmlEncoder struct{}
+
+func (xmlEncoder) MediaType() string {
+	return "application/xml"
+}
+
+func (xmlEncoder) Supports(any) bool {
+	return true
+}
+
+func (xmlEncoder) Encode(w io.Writer, v any) error {
+	n, err := toNode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	err = writeXML(enc, xmlRoot, n)
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func writeXML(enc *xml.Encoder, name string, n node) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case n.kind == '{':
+		for i, key := range n.keys {
+			err = writeXML(enc, key, n.children[i])
+			if err != nil {
+				return err
+			}
+		}
+	case n.kind == '[':
+		for _, child := range n.children {
+			err = writeXML(enc, xmlItem, child)
+			if err != nil {
+				return err
+			}
+		}
+	case n.value != nil:
+		err = enc.EncodeToken(xml.CharData(fmt.Sprint(n.value)))
+		if err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
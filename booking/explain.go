@@ -0,0 +1,121 @@
+package booking
+
+import "github.com/xsolrac87/booking/money"
+
+type Decision string
+
+const (
+	DecisionSelected Decision = "selected"
+	DecisionRejected Decision = "rejected"
+	DecisionInvalid  Decision = "invalid"
+)
+
+const (
+	reasonSelected = "part of the best combination"
+	reasonOverlap  = "overlaps with selected bookings"
+	reasonNoRoom   = "no room left on overlapping nights"
+	reasonNoGain   = "does not add profit"
+	reasonPinned   = "pinned by the request"
+	reasonExcluded = "excluded by the request"
+	reasonBlocked  = "does not fit next to pinned bookings"
+	reasonOutside  = "outside the planning horizon"
+)
+
+// BookingDecision tells what maximize did with a booking and why. ProfitDelta is how much the total profit changes
+// when the decision is flipped, that is, when a rejected booking is forced in or a selected one is left out. It's
+// zero when the decision cannot be flipped: bookings pinned or excluded by the request, bookings outside the planning
+// horizon, and bookings that do not fit next to the pinned ones.
+type BookingDecision struct {
+	RequestID    string       `json:"request_id"`
+	Status       Decision     `json:"status"`
+	Reason       string       `json:"reason"`
+	OverlapsWith []string     `json:"overlaps_with,omitempty"`
+	ProfitDelta  money.Amount `json:"profit_delta"`
+}
+
+// explain returns a decision for every booking, in the order they came, given the selected stays of p.
+func (m *MaximizeService) explain(bookings []Booking, p problem, selected []int) []BookingDecision {
+	var (
+		decisions = make([]BookingDecision, 0, len(bookings))
+		taken     = make([]bool, len(p.stays))
+		best      = p.score(selected)[profitWeight]
+		next      int
+	)
+	for _, i := range selected {
+		taken[i] = true
+	}
+
+	for _, b := range bookings {
+		if err := b.validate(); err != nil {
+			decisions = append(decisions, BookingDecision{
+				RequestID: b.RequestID,
+				Status:    DecisionInvalid,
+				Reason:    err.Error(),
+			})
+			continue
+		}
+
+		if _, ok := p.horizon.clip(b); !ok {
+			decisions = append(decisions, BookingDecision{
+				RequestID: b.RequestID,
+				Status:    DecisionRejected,
+				Reason:    reasonOutside,
+			})
+			continue
+		}
+
+		i := next
+		next++
+
+		switch {
+		case p.pinned[i]:
+			decisions = append(decisions, BookingDecision{
+				RequestID: b.RequestID,
+				Status:    DecisionSelected,
+				Reason:    reasonPinned,
+			})
+			continue
+		case p.excluded[i]:
+			decisions = append(decisions, BookingDecision{
+				RequestID: b.RequestID,
+				Status:    DecisionRejected,
+				Reason:    reasonExcluded,
+			})
+			continue
+		}
+
+		included, excluded := p.constraints()
+		if taken[i] {
+			excluded[i] = true
+		} else {
+			included[i] = true
+		}
+
+		decision := BookingDecision{RequestID: b.RequestID}
+		flipped, flippable := p.solve(included, excluded)
+		if flippable {
+			decision.ProfitDelta = money.FromMinor(p.score(flipped)[profitWeight] - best)
+		}
+
+		switch {
+		case taken[i]:
+			decision.Status = DecisionSelected
+			decision.Reason = reasonSelected
+		default:
+			decision.Status = DecisionRejected
+			decision.OverlapsWith = p.overlapping(i, selected)
+			switch {
+			case !flippable:
+				decision.Reason = reasonBlocked
+			case len(decision.OverlapsWith) == 0:
+				decision.Reason = reasonNoGain
+			case p.rooms == 1:
+				decision.Reason = reasonOverlap
+			default:
+				decision.Reason = reasonNoRoom
+			}
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
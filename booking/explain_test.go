@@ -0,0 +1,115 @@
+//go:build unit
+
+package booking
+
+import (
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMaximizeService_Explain(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2018-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2018-01-06"),
+			Nights:      10,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      10,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      0,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+		{
+			RequestID:   "E",
+			CheckIn:     parse("2018-02-12"),
+			Nights:      1,
+			SellingRate: money.MustParse("0.04"),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		opts     []MaximizeOption
+		expected []BookingDecision
+	}{
+		"single room": {
+			expected: []BookingDecision{
+				{RequestID: "A", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-70)},
+				{RequestID: "B", Status: DecisionRejected, Reason: reasonOverlap, OverlapsWith: []string{"A", "C"}, ProfitDelta: money.FromUnits(-70)},
+				{RequestID: "C", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-40)},
+				{RequestID: "D", Status: DecisionInvalid, Reason: errNightsNotPositive.Error()},
+				{RequestID: "E", Status: DecisionRejected, Reason: reasonNoGain, OverlapsWith: []string{}},
+			},
+		},
+		"two rooms": {
+			opts: []MaximizeOption{WithRooms(2)},
+			expected: []BookingDecision{
+				{RequestID: "A", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-100)},
+				{RequestID: "B", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-70)},
+				{RequestID: "C", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-40)},
+				{RequestID: "D", Status: DecisionInvalid, Reason: errNightsNotPositive.Error()},
+				{RequestID: "E", Status: DecisionRejected, Reason: reasonNoGain, OverlapsWith: []string{}},
+			},
+		},
+		"pinned and excluded": {
+			opts: []MaximizeOption{WithPinned("C"), WithExcluded("A")},
+			expected: []BookingDecision{
+				{RequestID: "A", Status: DecisionRejected, Reason: reasonExcluded},
+				{RequestID: "B", Status: DecisionRejected, Reason: reasonBlocked, OverlapsWith: []string{"C"}},
+				{RequestID: "C", Status: DecisionSelected, Reason: reasonPinned},
+				{RequestID: "D", Status: DecisionInvalid, Reason: errNightsNotPositive.Error()},
+				{RequestID: "E", Status: DecisionRejected, Reason: reasonNoGain, OverlapsWith: []string{}},
+			},
+		},
+		"horizon": {
+			opts: []MaximizeOption{WithHorizon(time.Time{}, parse("2018-01-31"), "")},
+			expected: []BookingDecision{
+				{RequestID: "A", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-70)},
+				{RequestID: "B", Status: DecisionRejected, Reason: reasonOverlap, OverlapsWith: []string{"A", "C"}, ProfitDelta: money.FromUnits(-70)},
+				{RequestID: "C", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-40)},
+				{RequestID: "D", Status: DecisionInvalid, Reason: errNightsNotPositive.Error()},
+				{RequestID: "E", Status: DecisionRejected, Reason: reasonOutside},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(bookings, append(tt.opts, WithExplanation())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.Explanation, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got.Explanation, tt.expected)
+			}
+		})
+	}
+
+	_, err := newMaximizeService(t).TopTotalProfits(bookings, 2, WithExplanation())
+	if err != errExplainTop {
+		t.Errorf("got: %v, expected: %v", err, errExplainTop)
+	}
+}
@@ -0,0 +1,159 @@
+package booking
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var errFrontierRooms = errors.New("frontier is only available for a single room")
+
+// FrontierPoint is a Pareto-optimal combination between total profit and occupied nights: no other combination
+// makes more profit without filling fewer nights, nor fills more nights without making less profit.
+type FrontierPoint struct {
+	MaximizeProfit
+	OccupiedNights int32 `json:"occupied_nights"`
+}
+
+// ProfitNightsFrontier returns every Pareto-optimal combination between total profit and occupied nights, from the
+// fewest nights and highest profit to the most nights and lowest profit, so filling X more nights costs the profit
+// difference between two points. Overlap rules, pinned and excluded bookings are honoured as in MaximTotalProfits.
+func (m *MaximizeService) ProfitNightsFrontier(bookings []Booking, opts ...MaximizeOption) ([]FrontierPoint, error) {
+	options, err := m.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer logOperation(options.loggerOrDefault(), "frontier", time.Now())
+	if options.rooms != nil && *options.rooms != 1 {
+		return nil, errFrontierRooms
+	}
+	if options.explain {
+		return nil, errExplainTop
+	}
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := m.problem(bookings, options)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.best()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := p.frontier()
+	points := make([]FrontierPoint, 0, len(schedules))
+	for _, indexes := range schedules {
+		var nights int32
+		for _, i := range indexes {
+			nights += p.stays[i].booking.Nights
+		}
+		points = append(points, FrontierPoint{
+			MaximizeProfit: m.calculateSchedule(p, indexes, options),
+			OccupiedNights: nights,
+		})
+	}
+	return points, nil
+}
+
+// frontierLabel is a schedule reached by the frontier, built as the stay taken last on top of a previous label.
+type frontierLabel struct {
+	profit, nights int64
+	stay, previous int
+}
+
+// frontier returns the Pareto-optimal schedules of a single unit between profit and nights, by nights. It follows
+// scheduleSingle, but each prefix of the stays sorted by end keeps its whole Pareto front instead of a single best
+// schedule: the front skipping the j-th stay merged with the one taking it on top of the stays ending before it.
+// Stays overlapping pinned ones are dropped first, so pinned stays can never be skipped.
+func (p problem) frontier() [][]int {
+	var order []int
+	for i, s := range p.stays {
+		if p.excluded[i] || !p.pinned[i] && p.overlapsPinned(s) {
+			continue
+		}
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return p.stays[order[i]].end < p.stays[order[j]].end
+	})
+
+	var (
+		labels = []frontierLabel{{stay: -1, previous: -1}}
+		fronts = make([][]int, len(order)+1) // fronts[j] is the Pareto front using the first j stays
+	)
+	fronts[0] = []int{0}
+
+	for j, idx := range order {
+		s := p.stays[idx]
+		previous := sort.Search(j, func(i int) bool {
+			return p.stays[order[i]].end > s.start
+		})
+
+		taken := make([]int, 0, len(fronts[previous]))
+		for _, l := range fronts[previous] {
+			labels = append(labels, frontierLabel{
+				profit:   labels[l].profit + s.weight[profitWeight],
+				nights:   labels[l].nights + int64(s.booking.Nights),
+				stay:     idx,
+				previous: l,
+			})
+			taken = append(taken, len(labels)-1)
+		}
+
+		if p.pinned[idx] {
+			fronts[j+1] = taken
+		} else {
+			fronts[j+1] = paretoFront(labels, append(append([]int(nil), fronts[j]...), taken...))
+		}
+	}
+
+	var schedules [][]int
+	for _, l := range fronts[len(order)] {
+		var selected []int
+		for ; labels[l].stay >= 0; l = labels[l].previous {
+			selected = append(selected, labels[l].stay)
+		}
+		if len(selected) > 0 {
+			schedules = append(schedules, selected)
+		}
+	}
+	return schedules
+}
+
+func (p problem) overlapsPinned(s stay) bool {
+	for i, pinned := range p.pinned {
+		if pinned && p.stays[i].overlaps(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// paretoFront returns the labels no other label beats on both profit and nights, by nights. Of two equal labels,
+// the first one listed is kept.
+func paretoFront(labels []frontierLabel, candidates []int) []int {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := labels[candidates[i]], labels[candidates[j]]
+		if a.nights != b.nights {
+			return a.nights > b.nights
+		}
+		return a.profit > b.profit
+	})
+
+	var front []int
+	for _, l := range candidates {
+		if len(front) == 0 || labels[l].profit > labels[front[len(front)-1]].profit {
+			front = append(front, l)
+		}
+	}
+
+	for i, j := 0, len(front)-1; i < j; i, j = i+1, j-1 {
+		front[i], front[j] = front[j], front[i]
+	}
+	return front
+}
@@ -0,0 +1,234 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMaximizeService_ProfitNightsFrontier(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2023-01-03"),
+			Nights:      2,
+			SellingRate: money.FromUnits(600),
+			Margin:      20,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2023-01-06"),
+			Nights:      3,
+			SellingRate: money.FromUnits(300),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2023-01-12"),
+			Nights:      1,
+			SellingRate: money.FromUnits(100),
+			Margin:      50,
+		},
+	}
+
+	tests := map[string]struct {
+		opts           []MaximizeOption
+		expectedIDS    [][]string
+		expectedProfit []money.Amount
+		expectedNights []int32
+		expectedErr    error
+	}{
+		"frontier": {
+			expectedIDS:    [][]string{{"B", "C", "D"}, {"A", "D"}},
+			expectedProfit: []money.Amount{money.FromUnits(200), money.FromUnits(150)},
+			expectedNights: []int32{6, 11},
+		},
+		"excluded": {
+			opts:           []MaximizeOption{WithExcluded("D")},
+			expectedIDS:    [][]string{{"B", "C"}, {"A"}},
+			expectedProfit: []money.Amount{money.FromUnits(150), money.FromUnits(100)},
+			expectedNights: []int32{5, 10},
+		},
+		"pinned": {
+			opts:           []MaximizeOption{WithPinned("A")},
+			expectedIDS:    [][]string{{"A", "D"}},
+			expectedProfit: []money.Amount{money.FromUnits(150)},
+			expectedNights: []int32{11},
+		},
+		"same-day turnover": {
+			opts:           []MaximizeOption{WithSameDayTurnover(), WithExcluded("B", "C")},
+			expectedIDS:    [][]string{{"A", "D"}},
+			expectedProfit: []money.Amount{money.FromUnits(150)},
+			expectedNights: []int32{11},
+		},
+		"rooms": {
+			opts:        []MaximizeOption{WithRooms(2)},
+			expectedErr: errFrontierRooms,
+		},
+		"explain": {
+			opts:        []MaximizeOption{WithExplanation()},
+			expectedErr: errExplainTop,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).ProfitNightsFrontier(bookings, tt.opts...)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+
+			var (
+				ids     [][]string
+				profits []money.Amount
+				nights  []int32
+			)
+			for _, point := range got {
+				ids = append(ids, point.RequestIDS)
+				profits = append(profits, point.TotalProfit)
+				nights = append(nights, point.OccupiedNights)
+			}
+
+			if !reflect.DeepEqual(ids, tt.expectedIDS) {
+				t.Errorf("got: %v, expected: %v", ids, tt.expectedIDS)
+			}
+			if !reflect.DeepEqual(profits, tt.expectedProfit) {
+				t.Errorf("got: %v, expected: %v", profits, tt.expectedProfit)
+			}
+			if !reflect.DeepEqual(nights, tt.expectedNights) {
+				t.Errorf("got: %v, expected: %v", nights, tt.expectedNights)
+			}
+		})
+	}
+}
+
+// TestMaximizeService_ProfitNightsFrontierOracle checks the frontier against every feasible combination.
+func TestMaximizeService_ProfitNightsFrontierOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(59))
+	for run := 0; run < 300; run++ {
+		var (
+			bookings = randomBookings(rnd, 1+rnd.Intn(9))
+			pinned   []string
+			excluded []string
+		)
+		for _, b := range bookings {
+			if !b.valid() {
+				continue
+			}
+			switch rnd.Intn(8) {
+			case 0:
+				pinned = append(pinned, b.RequestID)
+			case 1:
+				excluded = append(excluded, b.RequestID)
+			}
+		}
+
+		opts := []MaximizeOption{WithExcluded(excluded...)}
+		if len(pinned) > 0 {
+			opts = append(opts, WithPinned(pinned...))
+		}
+
+		got, err := newMaximizeService(t).ProfitNightsFrontier(bookings, opts...)
+		expected, feasible := bruteForceFrontier(bookings, pinned, excluded)
+		if !feasible {
+			if !errors.Is(err, errPinnedOverlap) {
+				t.Fatalf("run %d: got error: %v, expected: %v", run, err, errPinnedOverlap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+
+		points := make([][2]int64, 0, len(got))
+		for _, point := range got {
+			if !fits(selectedBookings(bookings, point.RequestIDS), 1) {
+				t.Fatalf("run %d: selection %v overlaps", run, point.RequestIDS)
+			}
+			if nights := totalNights(selectedBookings(bookings, point.RequestIDS)); nights != point.OccupiedNights {
+				t.Fatalf("run %d: got %d nights, expected: %d", run, point.OccupiedNights, nights)
+			}
+			points = append(points, [2]int64{int64(point.OccupiedNights), point.TotalProfit.Minor()})
+		}
+
+		if !reflect.DeepEqual(points, expected) {
+			t.Fatalf("run %d: got: %v, expected: %v", run, points, expected)
+		}
+	}
+}
+
+// bruteForceFrontier returns the nondominated nights and profit of every non-empty feasible combination, by nights.
+func bruteForceFrontier(bookings []Booking, pinned, excluded []string) ([][2]int64, bool) {
+	var (
+		points   [][2]int64
+		feasible bool
+		required = make(map[string]struct{}, len(pinned))
+		banned   = make(map[string]struct{}, len(excluded))
+	)
+	for _, id := range pinned {
+		required[id] = struct{}{}
+	}
+	for _, id := range excluded {
+		banned[id] = struct{}{}
+	}
+
+Subsets:
+	for mask := 0; mask < 1<<len(bookings); mask++ {
+		var subset []Booking
+		for i, b := range bookings {
+			_, isRequired := required[b.RequestID]
+			_, isBanned := banned[b.RequestID]
+			in := mask&(1<<i) != 0
+			if in && (isBanned || !b.valid()) || !in && isRequired {
+				continue Subsets
+			}
+			if in {
+				subset = append(subset, b)
+			}
+		}
+		if !fits(subset, 1) {
+			continue
+		}
+		feasible = true
+		if len(subset) > 0 {
+			points = append(points, [2]int64{int64(totalNights(subset)), totalProfit(subset).Minor()})
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i][0] != points[j][0] {
+			return points[i][0] > points[j][0]
+		}
+		return points[i][1] > points[j][1]
+	})
+
+	frontier := [][2]int64{}
+	for _, p := range points {
+		if len(frontier) == 0 || p[1] > frontier[0][1] {
+			frontier = append([][2]int64{p}, frontier...)
+		}
+	}
+	return frontier, feasible
+}
+
+func totalNights(bookings []Booking) int32 {
+	var total int32
+	for _, b := range bookings {
+		total += b.Nights
+	}
+	return total
+}
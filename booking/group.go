@@ -0,0 +1,172 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"sort"
+	"strings"
+)
+
+// GroupBy is a dimension the stats can be broken down by.
+type GroupBy string
+
+const (
+	// GroupByPartner groups by the request ID prefix before the first underscore, like acme in acme_AAAA.
+	GroupByPartner GroupBy = "partner"
+	// GroupByMonth groups by check-in month, like 2023-01.
+	GroupByMonth GroupBy = "month"
+	// GroupByWeek groups by check-in ISO week, like 2023-W05.
+	GroupByWeek GroupBy = "week"
+	// GroupByWeekday groups by check-in weekday, from Monday to Sunday.
+	GroupByWeekday GroupBy = "weekday"
+	// GroupByLengthOfStay groups by nights, in the buckets 1, 2-3, 4-6, 7-13 and 14+.
+	GroupByLengthOfStay GroupBy = "length_of_stay"
+)
+
+// unknownPartner groups the request IDs that do not tell their partner.
+const unknownPartner = "unknown"
+
+var errGroupBy = errors.New("group by should be partner, month, week, weekday or length_of_stay")
+
+// lengthOfStayBuckets are the lowest nights of every length of stay bucket, the last one being open.
+var lengthOfStayBuckets = []int32{1, 2, 4, 7, 14}
+
+// Group holds the profit per night stats of the valid bookings sharing the same key along a dimension.
+type Group struct {
+	Key      string `json:"key"`
+	Bookings int    `json:"bookings"`
+	ProfitPerNight
+}
+
+func (g GroupBy) valid() bool {
+	switch g {
+	case GroupByPartner, GroupByMonth, GroupByWeek, GroupByWeekday, GroupByLengthOfStay:
+		return true
+	}
+	return false
+}
+
+// key returns the group of b along g, and where the group goes among the others, lower first and then by key.
+func (g GroupBy) key(b Booking) (string, int) {
+	switch g {
+	case GroupByPartner:
+		partner, _, found := strings.Cut(b.RequestID, "_")
+		if !found || partner == "" {
+			return unknownPartner, 0
+		}
+		return partner, 0
+	case GroupByMonth:
+		return b.CheckIn.Format("2006-01"), 0
+	case GroupByWeek:
+		year, week := b.CheckIn.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week), 0
+	case GroupByWeekday:
+		// ISO weeks start on Monday.
+		return b.CheckIn.Weekday().String(), (int(b.CheckIn.Weekday()) + 6) % 7
+	default:
+		i := sort.Search(len(lengthOfStayBuckets), func(i int) bool {
+			return lengthOfStayBuckets[i] > b.Nights
+		}) - 1
+		if i == len(lengthOfStayBuckets)-1 {
+			return fmt.Sprintf("%d+", lengthOfStayBuckets[i]), i
+		}
+		if low, high := lengthOfStayBuckets[i], lengthOfStayBuckets[i+1]-1; low != high {
+			return fmt.Sprintf("%d-%d", low, high), i
+		}
+		return fmt.Sprint(lengthOfStayBuckets[i]), i
+	}
+}
+
+// accumulator keeps the running count, sum, min and max of profits per night, in constant memory.
+type accumulator struct {
+	count         int
+	sum, min, max money.Amount
+}
+
+// add accumulates v, failing with money.ErrRange once the sum does not fit in an amount, as a stream of bookings is
+// not capped.
+func (a *accumulator) add(v money.Amount) error {
+	sum, err := a.sum.CheckedAdd(v)
+	if err != nil {
+		return err
+	}
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count++
+	a.sum = sum
+	return nil
+}
+
+func (a accumulator) profitPerNight() ProfitPerNight {
+	var avg money.Amount
+	if a.count > 0 {
+		avg = a.sum.MulDiv(1, int64(a.count), statsRounding)
+	}
+	return NewProfitPerNight(avg, a.min, a.max)
+}
+
+type group struct {
+	position int
+	accumulator
+}
+
+// groups accumulates the profit per night of the bookings along every dimension at once.
+type groups map[GroupBy]map[string]*group
+
+func newGroups(dimensions []GroupBy) groups {
+	g := make(groups, len(dimensions))
+	for _, d := range dimensions {
+		g[d] = make(map[string]*group)
+	}
+	return g
+}
+
+func (g groups) add(b Booking, profitPerNight money.Amount) error {
+	for d, keys := range g {
+		key, position := d.key(b)
+		if keys[key] == nil {
+			keys[key] = &group{position: position}
+		}
+		err := keys[key].add(profitPerNight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the groups of every dimension in order, or nil when there are no dimensions.
+func (g groups) list() map[GroupBy][]Group {
+	if len(g) == 0 {
+		return nil
+	}
+
+	list := make(map[GroupBy][]Group, len(g))
+	for d, keys := range g {
+		names := make([]string, 0, len(keys))
+		for key := range keys {
+			names = append(names, key)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if a, b := keys[names[i]].position, keys[names[j]].position; a != b {
+				return a < b
+			}
+			return names[i] < names[j]
+		})
+
+		list[d] = make([]Group, 0, len(names))
+		for _, key := range names {
+			list[d] = append(list[d], Group{
+				Key:            key,
+				Bookings:       keys[key].count,
+				ProfitPerNight: keys[key].profitPerNight(),
+			})
+		}
+	}
+	return list
+}
@@ -0,0 +1,225 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGroupBy_Key(t *testing.T) {
+	tests := map[string]struct {
+		groupBy          GroupBy
+		booking          Booking
+		expectedKey      string
+		expectedPosition int
+	}{
+		"partner": {
+			groupBy:     GroupByPartner,
+			booking:     Booking{RequestID: "bookata_XY123"},
+			expectedKey: "bookata",
+		},
+		"partner with many underscores": {
+			groupBy:     GroupByPartner,
+			booking:     Booking{RequestID: "acme_AA_BB"},
+			expectedKey: "acme",
+		},
+		"partner without underscore": {
+			groupBy:     GroupByPartner,
+			booking:     Booking{RequestID: "1234567890"},
+			expectedKey: unknownPartner,
+		},
+		"partner without prefix": {
+			groupBy:     GroupByPartner,
+			booking:     Booking{RequestID: "_XY123"},
+			expectedKey: unknownPartner,
+		},
+		"month": {
+			groupBy:     GroupByMonth,
+			booking:     Booking{CheckIn: parse("2019-12-30")},
+			expectedKey: "2019-12",
+		},
+		"week across years": {
+			groupBy:     GroupByWeek,
+			booking:     Booking{CheckIn: parse("2019-12-30")},
+			expectedKey: "2020-W01",
+		},
+		"monday": {
+			groupBy:     GroupByWeekday,
+			booking:     Booking{CheckIn: parse("2019-12-30")},
+			expectedKey: "Monday",
+		},
+		"sunday": {
+			groupBy:          GroupByWeekday,
+			booking:          Booking{CheckIn: parse("2020-01-05")},
+			expectedKey:      "Sunday",
+			expectedPosition: 6,
+		},
+		"one night": {
+			groupBy:     GroupByLengthOfStay,
+			booking:     Booking{Nights: 1},
+			expectedKey: "1",
+		},
+		"three nights": {
+			groupBy:          GroupByLengthOfStay,
+			booking:          Booking{Nights: 3},
+			expectedKey:      "2-3",
+			expectedPosition: 1,
+		},
+		"thirteen nights": {
+			groupBy:          GroupByLengthOfStay,
+			booking:          Booking{Nights: 13},
+			expectedKey:      "7-13",
+			expectedPosition: 3,
+		},
+		"thirty nights": {
+			groupBy:          GroupByLengthOfStay,
+			booking:          Booking{Nights: 30},
+			expectedKey:      "14+",
+			expectedPosition: 4,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			key, position := tt.groupBy.key(tt.booking)
+			if key != tt.expectedKey || position != tt.expectedPosition {
+				t.Errorf("got: %s %d, expected: %s %d", key, position, tt.expectedKey, tt.expectedPosition)
+			}
+		})
+	}
+}
+
+func TestStatsService_SummarizeWithGroupBy(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "bookata_XY123",
+			CheckIn:     parse("2020-01-01"),
+			Nights:      5,
+			SellingRate: money.FromUnits(200),
+			Margin:      20,
+		},
+		{
+			RequestID:   "kayete_PP234",
+			CheckIn:     parse("2018-01-04"),
+			Nights:      4,
+			SellingRate: money.FromUnits(156),
+			Margin:      22,
+		},
+		{
+			RequestID:   "acme_AAAA",
+			CheckIn:     parse("2018-01-08"),
+			Nights:      1,
+			SellingRate: money.FromUnits(100),
+			Margin:      10,
+		},
+		{
+			RequestID:   "bookata_ZZ999",
+			CheckIn:     parse("2019-12-30"),
+			Nights:      14,
+			SellingRate: money.FromUnits(1400),
+			Margin:      10,
+		},
+		{
+			RequestID:   "1234567890",
+			CheckIn:     parse("2018-01-04"),
+			Nights:      7,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "acme_BBBB",
+			CheckIn:     parse("2018-01-04"),
+			Nights:      0,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		groupBy     []GroupBy
+		expected    map[GroupBy][]Group
+		expectedErr error
+	}{
+		"partner": {
+			groupBy: []GroupBy{GroupByPartner},
+			expected: map[GroupBy][]Group{
+				GroupByPartner: {
+					{Key: "acme", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "bookata", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(9), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(10)}},
+					{Key: "kayete", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("8.58"), MinNight: money.MustParse("8.58"), MaxNight: money.MustParse("8.58")}},
+					{Key: unknownPartner, Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+				},
+			},
+		},
+		"month and week": {
+			groupBy: []GroupBy{GroupByMonth, GroupByWeek},
+			expected: map[GroupBy][]Group{
+				GroupByMonth: {
+					{Key: "2018-01", Bookings: 3, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("9.53"), MinNight: money.MustParse("8.58"), MaxNight: money.FromUnits(10)}},
+					{Key: "2019-12", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "2020-01", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(8)}},
+				},
+				GroupByWeek: {
+					{Key: "2018-W01", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("9.29"), MinNight: money.MustParse("8.58"), MaxNight: money.FromUnits(10)}},
+					{Key: "2018-W02", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "2020-W01", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(9), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(10)}},
+				},
+			},
+		},
+		"weekday and length of stay": {
+			groupBy: []GroupBy{GroupByWeekday, GroupByLengthOfStay},
+			expected: map[GroupBy][]Group{
+				GroupByWeekday: {
+					{Key: "Monday", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "Wednesday", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(8)}},
+					{Key: "Thursday", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("9.29"), MinNight: money.MustParse("8.58"), MaxNight: money.FromUnits(10)}},
+				},
+				GroupByLengthOfStay: {
+					{Key: "1", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "4-6", Bookings: 2, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("8.29"), MinNight: money.FromUnits(8), MaxNight: money.MustParse("8.58")}},
+					{Key: "7-13", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+					{Key: "14+", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(10), MinNight: money.FromUnits(10), MaxNight: money.FromUnits(10)}},
+				},
+			},
+		},
+		"unknown dimension": {
+			groupBy:     []GroupBy{"country"},
+			expectedErr: errGroupBy,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newStatsService(t).Summarize(bookings, WithGroupBy(tt.groupBy...))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got.Groups, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got.Groups, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAccumulator_Add(t *testing.T) {
+	var a accumulator
+	err := a.add(money.FromMinor(math.MaxInt64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.add(money.FromMinor(1))
+	if !errors.Is(err, money.ErrRange) {
+		t.Errorf("got error: %v, expected: %v", err, money.ErrRange)
+	}
+	if a.count != 1 || a.sum != money.FromMinor(math.MaxInt64) {
+		t.Errorf("got %+v, expected the first amount alone", a)
+	}
+}
@@ -1,123 +1,675 @@
 package booking
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"github.com/xsolrac87/booking/timeparser"
 	"io"
-	"log"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
 	errInvalidHttpMethod  = errors.New("invalid HTTP method only post allowed")
+	errNotFound           = errors.New("no endpoint is served at this path")
 	errRequestBody        = errors.New("error reading request body")
+	errBodyTooLarge       = errors.New("request body is too large")
+	errRecovered          = errors.New("request handling panicked")
 	errInvalidRequestBody = errors.New("invalid request body")
+	errInvalidQueryParam  = errors.New("invalid query parameter")
 )
 
 type Handler struct {
 	statsService    *StatsService
 	maximizeService *MaximizeService
+	encoders        []Encoder
+	logger          *slog.Logger
 }
 
-func NewHandler(stats *StatsService, max *MaximizeService) (*Handler, error) {
-	return &Handler{
+type HandlerOption func(h *Handler) error
+
+// WithHandlerLogger sets the logger of the requests, slog.Default() by default. Every line of a request carries its
+// request ID and endpoint, and its number of bookings once decoded.
+func WithHandlerLogger(logger *slog.Logger) HandlerOption {
+	return func(h *Handler) error {
+		if logger == nil {
+			return errLogger
+		}
+		h.logger = logger
+		return nil
+	}
+}
+
+// WithEncoder adds e to the encoders the responses can be written with, in place of the one for the same media type.
+func WithEncoder(e Encoder) HandlerOption {
+	return func(h *Handler) error {
+		for i, registered := range h.encoders {
+			if registered.MediaType() == e.MediaType() {
+				h.encoders[i] = e
+				return nil
+			}
+		}
+		h.encoders = append(h.encoders, e)
+		return nil
+	}
+}
+
+func NewHandler(stats *StatsService, max *MaximizeService, opts ...HandlerOption) (*Handler, error) {
+	h := &Handler{
 		statsService:    stats,
 		maximizeService: max,
-	}, nil
+		encoders:        defaultEncoders(),
+		logger:          slog.Default(),
+	}
+	for _, opt := range opts {
+		err := opt(h)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
 }
 
 func (h *Handler) HandlerStats(w http.ResponseWriter, req *http.Request) {
-	defer func() {
-		err := req.Body.Close()
-		if err != nil {
-			log.Printf("failed to close response: %v\n", err)
-		}
-	}()
+	req, scope := h.begin(req, "stats")
+	defer h.end(req, scope)
+
+	src, err := h.handleStream(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	if src != nil {
+		h.streamStats(w, req, src)
+		return
+	}
 
-	log.Println("processing request from stats handler")
-	bookings, err := h.handleRequest(req)
+	bookings, unread, err := h.handleRequest(req)
 	if err != nil {
-		h.sendErrorResponse(w, err)
+		h.sendErrorResponse(w, req, err)
 		return
 	}
 
-	p := h.statsService.ProfitPerNight(bookings)
-	err = h.writeJSON(w, http.StatusOK, p)
+	opts, err := h.statsOptions(req)
 	if err != nil {
-		h.sendErrorResponse(w, err)
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	p, err := h.statsService.Summarize(bookings, append(opts, withUnread(unread), WithStatsLogger(scope.logger))...)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
 		return
 	}
 	return
 }
 
 func (h *Handler) HandlerMaximize(w http.ResponseWriter, req *http.Request) {
-	defer func() {
-		err := req.Body.Close()
+	req, scope := h.begin(req, "maximize")
+	defer h.end(req, scope)
+
+	bookings, _, err := h.handleRequest(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	opts, err := h.maximizeOptions(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	opts = append(opts, WithLogger(scope.logger))
+
+	top, err := h.intParam(req.URL.Query(), "top")
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	var p any
+	if top != nil {
+		p, err = h.maximizeService.ranking(bookings, *top, opts...)
+	} else {
+		p, err = h.maximizeService.selection(bookings, opts...)
+	}
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	return
+}
+
+func (h *Handler) HandlerFrontier(w http.ResponseWriter, req *http.Request) {
+	req, scope := h.begin(req, "frontier")
+	defer h.end(req, scope)
+
+	bookings, _, err := h.handleRequest(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	opts, err := h.maximizeOptions(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	p, err := h.maximizeService.ProfitNightsFrontier(bookings, append(opts, WithLogger(scope.logger))...)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	return
+}
+
+func (h *Handler) HandlerHistogram(w http.ResponseWriter, req *http.Request) {
+	req, scope := h.begin(req, "histogram")
+	defer h.end(req, scope)
+
+	bookings, _, err := h.handleRequest(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	bins, err := h.histogramBins(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	opts, err := h.statsScope(req.URL.Query())
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	p, err := h.statsService.Histogram(bookings, bins, append(opts, WithStatsLogger(scope.logger))...)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	return
+}
+
+func (h *Handler) HandlerCalendar(w http.ResponseWriter, req *http.Request) {
+	req, scope := h.begin(req, "calendar")
+	defer h.end(req, scope)
+
+	bookings, _, err := h.handleRequest(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	maximize, err := h.boolParam(req.URL.Query(), "maximize")
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	var p []CalendarNight
+	if maximize {
+		var opts []MaximizeOption
+		opts, err = h.maximizeOptions(req)
+		if err == nil {
+			p, err = h.maximizeService.Calendar(bookings, append(opts, WithLogger(scope.logger))...)
+		}
+	} else {
+		var opts []StatsOption
+		opts, err = h.statsScope(req.URL.Query())
+		if err == nil {
+			p, err = h.statsService.Calendar(bookings, append(opts, WithStatsLogger(scope.logger))...)
+		}
+	}
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+		return
+	}
+	return
+}
+
+// HandlerMethodNotAllowed answers the requests with a method their route does not allow, which the router lists in
+// the Allow header.
+func (h *Handler) HandlerMethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	h.sendErrorResponse(w, req, errInvalidHttpMethod)
+}
+
+// HandlerNotFound answers the requests to a path no route serves.
+func (h *Handler) HandlerNotFound(w http.ResponseWriter, req *http.Request) {
+	h.sendErrorResponse(w, req, errNotFound)
+}
+
+// HandlerInternalError answers the requests whose handling panicked, once recovered.
+func (h *Handler) HandlerInternalError(w http.ResponseWriter, req *http.Request) {
+	h.sendErrorResponse(w, req, errRecovered)
+}
+
+// handleRequest returns the bookings of the payload of req, and how many were left out as they could not be read.
+func (h *Handler) handleRequest(req *http.Request) ([]Booking, int, error) {
+	query := req.URL.Query()
+	mode, err := h.validationParam(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		bookings []Booking
+		unread   int
+	)
+	// the payload is a JSON array of bookings unless told otherwise.
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case csvContentType:
+		delimiter, err := newDelimiter(query.Get("delimiter"))
+		if err != nil {
+			return nil, 0, err
+		}
+		bookings, unread, err = decodeCSV(req.Body, delimiter, mode)
+		if err != nil {
+			return nil, 0, err
+		}
+	case ndjsonContentType:
+		src := newNDJSONSource(req.Body, mode)
+		bookings, err = collect(src)
+		if err != nil {
+			return nil, 0, err
+		}
+		unread = src.collector.unread
+	default:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, 0, readError(err)
+		}
+		bookings, unread, err = decodeBookings(body, mode)
 		if err != nil {
-			log.Printf("failed to close response: %v\n", err)
+			return nil, 0, err
 		}
-	}()
+	}
 
-	log.Println("processing request from maximize handler")
-	bookings, err := h.handleRequest(req)
+	h.scope(req).counted(len(bookings))
+	if len(bookings) == 0 {
+		return nil, 0, errInvalidRequestBody
+	}
+	return bookings, unread, nil
+}
+
+// handleStream returns the source of the bookings of an NDJSON request, read as they are needed, or nil for any other
+// payload.
+func (h *Handler) handleStream(req *http.Request) (*ndjsonSource, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != ndjsonContentType {
+		return nil, nil
+	}
+
+	mode, err := h.validationParam(req.URL.Query())
 	if err != nil {
-		h.sendErrorResponse(w, err)
+		return nil, err
+	}
+	return newNDJSONSource(req.Body, mode), nil
+}
+
+// streamStats answers req with the stats of the bookings of src, summarised as they are read.
+func (h *Handler) streamStats(w http.ResponseWriter, req *http.Request, src *ndjsonSource) {
+	opts, err := h.statsOptions(req)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
 		return
 	}
 
-	p := h.maximizeService.MaximTotalProfits(bookings)
-	err = h.writeJSON(w, http.StatusOK, p)
+	scope := h.scope(req)
+	p, err := h.statsService.SummarizeStream(src, append(opts, WithStatsLogger(scope.logger))...)
+	scope.counted(src.read)
 	if err != nil {
-		h.sendErrorResponse(w, err)
+		h.sendErrorResponse(w, req, err)
 		return
 	}
-	return
+	if src.read == 0 {
+		h.sendErrorResponse(w, req, errInvalidRequestBody)
+		return
+	}
+
+	err = h.write(w, req, http.StatusOK, p)
+	if err != nil {
+		h.sendErrorResponse(w, req, err)
+	}
 }
 
-func (h *Handler) handleRequest(req *http.Request) ([]Booking, error) {
-	if req.Method != http.MethodPost {
-		return nil, errInvalidHttpMethod
+// validationParam returns the validation mode of the payload, empty to fail on the first booking that cannot be read.
+func (h *Handler) validationParam(query url.Values) (ValidationMode, error) {
+	mode := ValidationMode(query.Get("validation"))
+	switch mode {
+	case "", ValidationLenient, ValidationStrict:
+		return mode, nil
 	}
+	return "", fmt.Errorf("%w: %s", errValidationMode, mode)
+}
 
-	body, err := io.ReadAll(req.Body)
+func (h *Handler) statsOptions(req *http.Request) ([]StatsOption, error) {
+	query := req.URL.Query()
+	opts, err := h.statsScope(query)
 	if err != nil {
-		return nil, errRequestBody
+		return nil, err
 	}
 
-	var bookings []Booking
-	err = json.Unmarshal(body, &bookings)
+	detailed, err := h.boolParam(query, "detailed")
 	if err != nil {
 		return nil, err
 	}
+	if detailed {
+		opts = append(opts, WithDistribution())
+	}
 
-	if len(bookings) == 0 {
-		return nil, errInvalidRequestBody
-	}
-	return bookings, nil
-}
-
-func (h *Handler) sendErrorResponse(w http.ResponseWriter, err error) {
-	var statusCode int
-	switch {
-	case errors.Is(err, errInvalidHttpMethod):
-		statusCode = http.StatusMethodNotAllowed
-	case errors.Is(err, errRequestBody),
-		errors.Is(err, errInvalidRequestBody),
-		errors.Is(err, errRequestIDMissing),
-		errors.Is(err, errCheckInMissing),
-		errors.Is(err, errNightsMissing),
-		errors.Is(err, errSellingRateMissing),
-		errors.Is(err, errMarginMissing):
-		statusCode = http.StatusBadRequest
-	default:
-		statusCode = http.StatusInternalServerError
+	if groupBy := h.listParam(query, "group_by"); len(groupBy) > 0 {
+		dimensions := make([]GroupBy, 0, len(groupBy))
+		for _, d := range groupBy {
+			dimensions = append(dimensions, GroupBy(d))
+		}
+		opts = append(opts, WithGroupBy(dimensions...))
+	}
+	return opts, nil
+}
+
+// statsScope returns the planning horizon, reporting currency and duplicate policy options of the stats, if any.
+func (h *Handler) statsScope(query url.Values) ([]StatsOption, error) {
+	from, to, mode, err := h.horizonParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []StatsOption
+	if !from.IsZero() || !to.IsZero() {
+		opts = append(opts, WithStatsHorizon(from, to, mode))
+	}
+	if currency := query.Get("currency"); currency != "" {
+		opts = append(opts, WithStatsCurrency(strings.ToUpper(currency)))
+	}
+	if duplicates := query.Get("duplicates"); duplicates != "" {
+		opts = append(opts, WithStatsDuplicates(DuplicatePolicy(duplicates)))
+	}
+	return opts, nil
+}
+
+// histogramBins returns the bins set by exactly one of the width, edges or quantiles query parameters.
+func (h *Handler) histogramBins(req *http.Request) (Bins, error) {
+	var (
+		query = req.URL.Query()
+		bins  []Bins
+	)
+
+	width, err := h.amountParam(query, "width")
+	if err != nil {
+		return Bins{}, err
+	}
+	if width != nil {
+		bins = append(bins, WidthBins(*width))
+	}
+
+	var edges []money.Amount
+	for _, v := range h.listParam(query, "edges") {
+		edge, err := money.Parse(v)
+		if err != nil {
+			return Bins{}, fmt.Errorf("%w %s: %s", errInvalidQueryParam, "edges", v)
+		}
+		edges = append(edges, edge)
+	}
+	if len(edges) > 0 {
+		bins = append(bins, EdgeBins(edges...))
+	}
+
+	quantiles, err := h.intParam(query, "quantiles")
+	if err != nil {
+		return Bins{}, err
+	}
+	if quantiles != nil {
+		bins = append(bins, QuantileBins(*quantiles))
+	}
+
+	if len(bins) != 1 {
+		return Bins{}, errBins
+	}
+	return bins[0], nil
+}
+
+func (h *Handler) maximizeOptions(req *http.Request) ([]MaximizeOption, error) {
+	var (
+		query = req.URL.Query()
+		opts  []MaximizeOption
+	)
+
+	rooms, err := h.intParam(query, "rooms")
+	if err != nil {
+		return nil, err
+	}
+	if rooms != nil {
+		opts = append(opts, WithRooms(*rooms))
+	}
+
+	bufferNights, err := h.intParam(query, "buffer_nights")
+	if err != nil {
+		return nil, err
+	}
+	if bufferNights != nil {
+		opts = append(opts, WithBufferNights(*bufferNights))
+	}
+
+	from, to, mode, err := h.horizonParams(query)
+	if err != nil {
+		return nil, err
 	}
+	if !from.IsZero() || !to.IsZero() {
+		opts = append(opts, WithHorizon(from, to, mode))
+	}
+
+	if objective := query.Get("objective"); objective != "" {
+		opts = append(opts, WithObjective(Objective(objective)))
+	}
+
+	explain, err := h.boolParam(query, "explain")
+	if err != nil {
+		return nil, err
+	}
+	if explain {
+		opts = append(opts, WithExplanation())
+	}
+
+	if pinned := h.listParam(query, "pinned"); len(pinned) > 0 {
+		opts = append(opts, WithPinned(pinned...))
+	}
+	if excluded := h.listParam(query, "excluded"); len(excluded) > 0 {
+		opts = append(opts, WithExcluded(excluded...))
+	}
+
+	if currency := query.Get("currency"); currency != "" {
+		opts = append(opts, WithCurrency(strings.ToUpper(currency)))
+	}
+	if duplicates := query.Get("duplicates"); duplicates != "" {
+		opts = append(opts, WithDuplicates(DuplicatePolicy(duplicates)))
+	}
+	return opts, nil
+}
+
+// horizonParams returns the planning horizon bounds, zero when not set, and what to do with the stays straddling them.
+func (h *Handler) horizonParams(query url.Values) (time.Time, time.Time, HorizonMode, error) {
+	from, err := h.dateParam(query, "from")
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+
+	to, err := h.dateParam(query, "to")
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+	return from, to, HorizonMode(query.Get("straddling")), nil
+}
 
-	http.Error(w, err.Error(), statusCode)
+// dateParam returns the YYYY-MM-DD date of the query parameter name, or the zero time when it's not set.
+func (h *Handler) dateParam(query url.Values, name string) (time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := timeparser.ToTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w %s: %s", errInvalidQueryParam, name, v)
+	}
+	return t, nil
 }
 
-func (h *Handler) writeJSON(w http.ResponseWriter, s int, v any) error {
+// intParam returns the integer value of the query parameter name, or nil when it's not set.
+func (h *Handler) intParam(query url.Values, name string) (*int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", errInvalidQueryParam, name, v)
+	}
+	return &i, nil
+}
+
+// amountParam returns the decimal amount of the query parameter name, or nil when it's not set.
+func (h *Handler) amountParam(query url.Values, name string) (*money.Amount, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	a, err := money.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", errInvalidQueryParam, name, v)
+	}
+	return &a, nil
+}
+
+// boolParam returns the boolean value of the query parameter name, false when it's not set.
+func (h *Handler) boolParam(query url.Values, name string) (bool, error) {
+	v := query.Get(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%w %s: %s", errInvalidQueryParam, name, v)
+	}
+	return b, nil
+}
+
+// listParam returns the comma separated values of the query parameter name, which can also be repeated.
+func (h *Handler) listParam(query url.Values, name string) []string {
+	var list []string
+	for _, v := range query[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// readError returns the error answering a failure reading a request body, telling apart the bodies over the size
+// limit of the server.
+func readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w: over %d bytes", errBodyTooLarge, tooLarge.Limit)
+	}
+	return errRequestBody
+}
+
+// sendErrorResponse answers req with the problem document of err.
+func (h *Handler) sendErrorResponse(w http.ResponseWriter, req *http.Request, err error) {
+	scope := h.scope(req)
+	p := newProblem(req, scope.requestID, err)
+	logger := scope.logger
+	if p.Status == http.StatusInternalServerError {
+		logger.Error("request failed", "error", err)
+	} else {
+		logger.Debug("request rejected", "code", p.Code, "error", err)
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set(RequestIDHeader, p.CorrelationID)
+	w.WriteHeader(p.Status)
+	err = json.NewEncoder(w).Encode(p)
+	if err != nil {
+		logger.Warn("failed to write error response", "error", err)
+	}
+}
+
+// write answers req with v in the media type negotiated with its Accept header, or set by its format query param.
+// The body is encoded before anything is written, so encoding errors can still be answered.
+func (h *Handler) write(w http.ResponseWriter, req *http.Request, s int, v any) error {
+	accept := req.Header.Get("Accept")
+	if format := req.URL.Query().Get("format"); format != "" {
+		mediaType, ok := formats[format]
+		if !ok {
+			return fmt.Errorf("%w: %s", errFormat, format)
+		}
+		accept = mediaType
+	}
+
+	e, err := negotiate(h.encoders, accept, v)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	err = e.Encode(&body, v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", e.MediaType())
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(s)
-	w.Header().Add("Content-Type", "application/json")
-	return json.NewEncoder(w).Encode(v)
+	_, err = body.WriteTo(w)
+	if err != nil {
+		h.scope(req).logger.Warn("failed to write response", "error", err)
+	}
+	return nil
 }
@@ -5,18 +5,25 @@ package booking
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestHandler_HandlerStats(t *testing.T) {
 	tests := map[string]struct {
-		payload      []byte
-		method       string
-		expectedCode int
-		expected     ProfitPerNight
+		payload              []byte
+		method               string
+		query                string
+		expectedCode         int
+		expected             ProfitPerNight
+		expectedDistribution *Distribution
+		expectedGroups       map[GroupBy][]Group
 	}{
 		"pdf first example payload": {
 			payload: []byte(`
@@ -40,9 +47,9 @@ func TestHandler_HandlerStats(t *testing.T) {
 			method:       http.MethodPost,
 			expectedCode: http.StatusOK,
 			expected: ProfitPerNight{
-				AvgNight: 8.29,
-				MinNight: 8,
-				MaxNight: 8.58,
+				AvgNight: money.MustParse("8.29"),
+				MinNight: money.FromUnits(8),
+				MaxNight: money.MustParse("8.58"),
 			},
 		},
 		"pdf second example payload": {
@@ -74,9 +81,9 @@ func TestHandler_HandlerStats(t *testing.T) {
 			method:       http.MethodPost,
 			expectedCode: http.StatusOK,
 			expected: ProfitPerNight{
-				AvgNight: 10.80,
-				MinNight: 10,
-				MaxNight: 12.1,
+				AvgNight: money.MustParse("10.80"),
+				MinNight: money.FromUnits(10),
+				MaxNight: money.MustParse("12.1"),
 			},
 		},
 		"invalid payload": {
@@ -92,6 +99,49 @@ func TestHandler_HandlerStats(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			expected:     ProfitPerNight{},
 		},
+		"decimal selling rates": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 3,
+  						"selling_rate": 199.99,
+  						"margin": 15
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 1,
+  						"selling_rate": "20.05",
+  						"margin": 10
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			expectedCode: http.StatusOK,
+			expected: ProfitPerNight{
+				AvgNight: money.MustParse("6.01"),
+				MinNight: money.MustParse("2.01"),
+				MaxNight: money.FromUnits(10),
+			},
+		},
+		"selling rate with more than 2 decimals": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 3,
+  						"selling_rate": 199.999,
+  						"margin": 15
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			expectedCode: http.StatusBadRequest,
+			expected:     ProfitPerNight{},
+		},
 		"payload with incorrect format": {
 			payload: []byte(`
 				[
@@ -102,24 +152,202 @@ func TestHandler_HandlerStats(t *testing.T) {
 				]
 			`),
 			method:       http.MethodPost,
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 			expected:     ProfitPerNight{},
 		},
-		"invalid method": {
+		"horizon": {
 			payload: []byte(`
 				[
 					{
-  						"request_id": "trivoltio_ZX69",
-  						"check_in": "2020-01-07",
-  						"nights": 1,
-  						"selling_rate": 49,
-  						"margin": 21
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
 					}
 				]
 			`),
-			method:       http.MethodGet,
-			expectedCode: http.StatusMethodNotAllowed,
-			expected:     ProfitPerNight{},
+			method:       http.MethodPost,
+			query:        "?to=2020-01-05",
+			expectedCode: http.StatusOK,
+			expected: ProfitPerNight{
+				AvgNight: money.FromUnits(8),
+				MinNight: money.FromUnits(8),
+				MaxNight: money.FromUnits(8),
+			},
+		},
+		"detailed": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?detailed=true",
+			expectedCode: http.StatusOK,
+			expected: ProfitPerNight{
+				AvgNight: money.MustParse("8.29"),
+				MinNight: money.FromUnits(8),
+				MaxNight: money.MustParse("8.58"),
+			},
+			expectedDistribution: &Distribution{
+				MedianNight: money.MustParse("8.29"),
+				P90Night:    money.MustParse("8.52"),
+				P95Night:    money.MustParse("8.55"),
+				P99Night:    money.MustParse("8.57"),
+				StdDevNight: money.MustParse("0.29"),
+				Valid:       2,
+			},
+		},
+		"invalid detailed": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?detailed=sure",
+			expectedCode: http.StatusBadRequest,
+		},
+		"group by partner and weekday": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?group_by=partner,weekday",
+			expectedCode: http.StatusOK,
+			expected: ProfitPerNight{
+				AvgNight: money.MustParse("8.29"),
+				MinNight: money.FromUnits(8),
+				MaxNight: money.MustParse("8.58"),
+			},
+			expectedGroups: map[GroupBy][]Group{
+				GroupByPartner: {
+					{Key: "bookata", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(8)}},
+					{Key: "kayete", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("8.58"), MinNight: money.MustParse("8.58"), MaxNight: money.MustParse("8.58")}},
+				},
+				GroupByWeekday: {
+					{Key: "Wednesday", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(8), MinNight: money.FromUnits(8), MaxNight: money.FromUnits(8)}},
+					{Key: "Saturday", Bookings: 1, ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("8.58"), MinNight: money.MustParse("8.58"), MaxNight: money.MustParse("8.58")}},
+				},
+			},
+		},
+		"invalid group by": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?group_by=country",
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid horizon date": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?from=2020-13-01",
+			expectedCode: http.StatusBadRequest,
+		},
+		"horizon from after to": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "bookata_XY123",
+  						"check_in": "2020-01-01",
+  						"nights": 5,
+  						"selling_rate": 200,
+  						"margin": 20
+					},
+					{
+  						"request_id": "kayete_PP234",
+  						"check_in": "2020-01-04",
+  						"nights": 4,
+  						"selling_rate": 156,
+  						"margin": 22
+					}
+				]
+			`),
+			method:       http.MethodPost,
+			query:        "?from=2020-01-05&to=2020-01-01",
+			expectedCode: http.StatusBadRequest,
 		},
 	}
 
@@ -129,22 +357,28 @@ func TestHandler_HandlerStats(t *testing.T) {
 			t.Parallel()
 
 			wr := httptest.NewRecorder()
-			req := httptest.NewRequest(tt.method, "/stats", bytes.NewBuffer([]byte(tt.payload)))
+			req := httptest.NewRequest(tt.method, "/stats"+tt.query, bytes.NewBuffer([]byte(tt.payload)))
 
-			HandleR.HandlerStats(wr, req)
+			newHandler(t).HandlerStats(wr, req)
 			if wr.Code != tt.expectedCode {
 				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
 			}
 
 			if wr.Code == http.StatusOK {
-				var got ProfitPerNight
+				var got Summary
 				err := json.Unmarshal(wr.Body.Bytes(), &got)
 				if err != nil {
 					t.Error(err)
 				}
 
-				if got != tt.expected {
-					t.Errorf("got: %v, expected: %v", got, tt.expected)
+				if got.ProfitPerNight != tt.expected {
+					t.Errorf("got: %v, expected: %v", got.ProfitPerNight, tt.expected)
+				}
+				if !reflect.DeepEqual(got.Distribution, tt.expectedDistribution) {
+					t.Errorf("got: %+v, expected: %+v", got.Distribution, tt.expectedDistribution)
+				}
+				if !reflect.DeepEqual(got.Groups, tt.expectedGroups) {
+					t.Errorf("got: %+v, expected: %+v", got.Groups, tt.expectedGroups)
 				}
 			}
 		})
@@ -154,6 +388,7 @@ func TestHandler_HandlerStats(t *testing.T) {
 func TestHandler_HandlerMaximize(t *testing.T) {
 	tests := map[string]struct {
 		payload      []byte
+		query        string
 		expectedCode int
 		expected     MaximizeProfit
 	}{
@@ -186,11 +421,11 @@ func TestHandler_HandlerMaximize(t *testing.T) {
 			expectedCode: http.StatusOK,
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"A", "C"},
-				TotalProfit: 140,
+				TotalProfit: money.FromUnits(140),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 7,
-					MinNight: 4,
-					MaxNight: 10,
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
 				},
 			},
 		},
@@ -230,11 +465,11 @@ func TestHandler_HandlerMaximize(t *testing.T) {
 			expectedCode: http.StatusOK,
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"bookata_XY123", "acme_AAAAA"},
-				TotalProfit: 88,
+				TotalProfit: money.FromUnits(88),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 10,
-					MinNight: 8,
-					MaxNight: 12,
+					AvgNight: money.FromUnits(10),
+					MinNight: money.FromUnits(8),
+					MaxNight: money.FromUnits(12),
 				},
 			},
 		},
@@ -267,45 +502,1438 @@ func TestHandler_HandlerMaximize(t *testing.T) {
 			expectedCode: http.StatusOK,
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"A"},
-				TotalProfit: 400,
+				TotalProfit: money.FromUnits(400),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 80,
-					MinNight: 80,
-					MaxNight: 80,
+					AvgNight: money.FromUnits(80),
+					MinNight: money.FromUnits(80),
+					MaxNight: money.FromUnits(80),
 				},
 			},
 		},
-	}
-
-	for name, tt := range tests {
-		tt := tt
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
-
-			wr := httptest.NewRecorder()
-			req := httptest.NewRequest(http.MethodPost, "/maximize", bytes.NewBuffer([]byte(tt.payload)))
-
-			HandleR.HandlerMaximize(wr, req)
-			if wr.Code != tt.expectedCode {
-				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
-			}
-
-			var got MaximizeProfit
-			err := json.Unmarshal(wr.Body.Bytes(), &got)
-			if err != nil {
-				t.Error(err)
-			}
-
-			if !reflect.DeepEqual(got.RequestIDS, tt.expected.RequestIDS) {
-				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected.RequestIDS)
-			}
-
-			if got.TotalProfit != tt.expected.TotalProfit {
-				t.Errorf("got: %d, expected: %d", got.TotalProfit, tt.expected.TotalProfit)
-			}
-
-			if got.ProfitPerNight != tt.expected.ProfitPerNight {
-				t.Errorf("got: %v, expected: %v", got.ProfitPerNight, tt.expected.ProfitPerNight)
+		"horizon prorated ( A + C )": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?from=2018-01-05&to=2018-01-20&straddling=prorate",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "C"},
+				TotalProfit: money.FromUnits(96),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
+				},
+			},
+		},
+		"invalid straddling": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?from=2018-01-05&straddling=clip",
+			expectedCode: http.StatusBadRequest,
+		},
+		"pinned outside horizon": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?to=2018-01-04&pinned=B",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"two rooms ( A + B + C )": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?rooms=2",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "B", "C"},
+				TotalProfit: money.FromUnits(210),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
+				},
+				Rooms: []RoomAssignment{
+					{Room: 1, RequestIDS: []string{"A", "C"}},
+					{Room: 2, RequestIDS: []string{"B"}},
+				},
+			},
+		},
+		"invalid rooms": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?rooms=zero",
+			expectedCode: http.StatusBadRequest,
+		},
+		"explain": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 0
+					}
+				]
+			`),
+			query:        "?explain=true",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A"},
+				TotalProfit: money.FromUnits(100),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.FromUnits(10),
+					MinNight: money.FromUnits(10),
+					MaxNight: money.FromUnits(10),
+				},
+				Explanation: []BookingDecision{
+					{RequestID: "A", Status: DecisionSelected, Reason: reasonSelected, ProfitDelta: money.FromUnits(-100)},
+					{RequestID: "B", Status: DecisionInvalid, Reason: errMarginNotPositive.Error()},
+				},
+			},
+		},
+		"invalid explain": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?explain=maybe",
+			expectedCode: http.StatusBadRequest,
+		},
+		"pinned B": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?pinned=B",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"B"},
+				TotalProfit: money.FromUnits(70),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(7),
+					MaxNight: money.FromUnits(7),
+				},
+			},
+		},
+		"excluded A and C": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?excluded=A&excluded=C",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"B"},
+				TotalProfit: money.FromUnits(70),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(7),
+					MaxNight: money.FromUnits(7),
+				},
+			},
+		},
+		"pinned overlapping bookings": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 10,
+  						"selling_rate": 700,
+  						"margin": 10
+					},
+					{
+  						"request_id": "C",
+  						"check_in": "2018-01-12",
+  						"nights": 10,
+  						"selling_rate": 400,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?pinned=A,B",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"same day turnover": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2023-01-01",
+  						"nights": 2,
+  						"selling_rate": 100,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2023-01-03",
+  						"nights": 2,
+  						"selling_rate": 200,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?buffer_nights=0",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "B"},
+				TotalProfit: money.FromUnits(30),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.MustParse("7.5"),
+					MinNight: money.FromUnits(5),
+					MaxNight: money.FromUnits(10),
+				},
+			},
+		},
+		"negative buffer nights": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?buffer_nights=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+		"nights objective": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					},
+					{
+  						"request_id": "B",
+  						"check_in": "2018-01-06",
+  						"nights": 20,
+  						"selling_rate": 700,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?objective=nights",
+			expectedCode: http.StatusOK,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"B"},
+				TotalProfit: money.FromUnits(70),
+				ProfitPerNight: ProfitPerNight{
+					AvgNight: money.MustParse("3.5"),
+					MinNight: money.MustParse("3.5"),
+					MaxNight: money.MustParse("3.5"),
+				},
+				Objective:      ObjectiveNights,
+				ObjectiveValue: money.FromUnits(20),
+			},
+		},
+		"unknown objective": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?objective=occupancy",
+			expectedCode: http.StatusBadRequest,
+		},
+		"non positive rooms": {
+			payload: []byte(`
+				[
+					{
+  						"request_id": "A",
+  						"check_in": "2018-01-01",
+  						"nights": 10,
+  						"selling_rate": 1000,
+  						"margin": 10
+					}
+				]
+			`),
+			query:        "?rooms=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		"too many rooms": {
+			payload:      []byte(`[{"request_id": "A", "check_in": "2018-01-01", "nights": 10, "selling_rate": 1000, "margin": 10}]`),
+			query:        "?rooms=5000000",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/maximize"+tt.query, bytes.NewBuffer([]byte(tt.payload)))
+
+			newHandler(t).HandlerMaximize(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got MaximizeProfit
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expected.RequestIDS) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected.RequestIDS)
+			}
+
+			if got.TotalProfit != tt.expected.TotalProfit {
+				t.Errorf("got: %v, expected: %v", got.TotalProfit, tt.expected.TotalProfit)
+			}
+
+			if got.ProfitPerNight != tt.expected.ProfitPerNight {
+				t.Errorf("got: %v, expected: %v", got.ProfitPerNight, tt.expected.ProfitPerNight)
+			}
+
+			if !reflect.DeepEqual(got.Rooms, tt.expected.Rooms) {
+				t.Errorf("got: %v, expected: %v", got.Rooms, tt.expected.Rooms)
+			}
+
+			if !reflect.DeepEqual(got.Explanation, tt.expected.Explanation) {
+				t.Errorf("got: %v, expected: %v", got.Explanation, tt.expected.Explanation)
+			}
+
+			if tt.expected.Objective != "" && got.Objective != tt.expected.Objective {
+				t.Errorf("got: %s, expected: %s", got.Objective, tt.expected.Objective)
+			}
+
+			if tt.expected.Objective != "" && got.ObjectiveValue != tt.expected.ObjectiveValue {
+				t.Errorf("got: %s, expected: %s", got.ObjectiveValue, tt.expected.ObjectiveValue)
+			}
+		})
+	}
+}
+
+func TestHandler_HandlerMaximizeTop(t *testing.T) {
+	payload := []byte(`
+		[
+			{
+				"request_id": "A",
+				"check_in": "2018-01-01",
+				"nights": 10,
+				"selling_rate": 1000,
+				"margin": 10
+			},
+			{
+				"request_id": "B",
+				"check_in": "2018-01-06",
+				"nights": 10,
+				"selling_rate": 700,
+				"margin": 10
+			},
+			{
+				"request_id": "C",
+				"check_in": "2018-01-12",
+				"nights": 10,
+				"selling_rate": 400,
+				"margin": 10
+			}
+		]
+	`)
+
+	tests := map[string]struct {
+		query        string
+		expectedCode int
+		expected     []MaximizeProfit
+	}{
+		"best two": {
+			query:        "?top=2",
+			expectedCode: http.StatusOK,
+			expected: []MaximizeProfit{
+				{
+					RequestIDS:  []string{"A", "C"},
+					TotalProfit: money.FromUnits(140),
+					ProfitPerNight: ProfitPerNight{
+						AvgNight: money.FromUnits(7),
+						MinNight: money.FromUnits(4),
+						MaxNight: money.FromUnits(10),
+					},
+					Objective:      ObjectiveProfit,
+					ObjectiveValue: money.FromUnits(140),
+				},
+				{
+					RequestIDS:  []string{"A"},
+					TotalProfit: money.FromUnits(100),
+					ProfitPerNight: ProfitPerNight{
+						AvgNight: money.FromUnits(10),
+						MinNight: money.FromUnits(10),
+						MaxNight: money.FromUnits(10),
+					},
+					Objective:      ObjectiveProfit,
+					ObjectiveValue: money.FromUnits(100),
+				},
+			},
+		},
+		"invalid top": {
+			query:        "?top=two",
+			expectedCode: http.StatusBadRequest,
+		},
+		"top out of range": {
+			query:        "?top=0",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/maximize"+tt.query, bytes.NewBuffer(payload))
+
+			newHandler(t).HandlerMaximize(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got []MaximizeProfit
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandler_HandlerFrontier(t *testing.T) {
+	payload := []byte(`
+		[
+			{
+				"request_id": "A",
+				"check_in": "2018-01-01",
+				"nights": 10,
+				"selling_rate": 1000,
+				"margin": 10
+			},
+			{
+				"request_id": "B",
+				"check_in": "2018-01-03",
+				"nights": 2,
+				"selling_rate": 600,
+				"margin": 20
+			}
+		]
+	`)
+
+	tests := map[string]struct {
+		query        string
+		expectedCode int
+		expected     []FrontierPoint
+	}{
+		"frontier": {
+			expectedCode: http.StatusOK,
+			expected: []FrontierPoint{
+				{
+					MaximizeProfit: MaximizeProfit{
+						RequestIDS:  []string{"B"},
+						TotalProfit: money.FromUnits(120),
+						ProfitPerNight: ProfitPerNight{
+							AvgNight: money.FromUnits(60),
+							MinNight: money.FromUnits(60),
+							MaxNight: money.FromUnits(60),
+						},
+						Objective:      ObjectiveProfit,
+						ObjectiveValue: money.FromUnits(120),
+					},
+					OccupiedNights: 2,
+				},
+				{
+					MaximizeProfit: MaximizeProfit{
+						RequestIDS:  []string{"A"},
+						TotalProfit: money.FromUnits(100),
+						ProfitPerNight: ProfitPerNight{
+							AvgNight: money.FromUnits(10),
+							MinNight: money.FromUnits(10),
+							MaxNight: money.FromUnits(10),
+						},
+						Objective:      ObjectiveProfit,
+						ObjectiveValue: money.FromUnits(100),
+					},
+					OccupiedNights: 10,
+				},
+			},
+		},
+		"pinned": {
+			query:        "?pinned=A",
+			expectedCode: http.StatusOK,
+			expected: []FrontierPoint{
+				{
+					MaximizeProfit: MaximizeProfit{
+						RequestIDS:  []string{"A"},
+						TotalProfit: money.FromUnits(100),
+						ProfitPerNight: ProfitPerNight{
+							AvgNight: money.FromUnits(10),
+							MinNight: money.FromUnits(10),
+							MaxNight: money.FromUnits(10),
+						},
+						Objective:      ObjectiveProfit,
+						ObjectiveValue: money.FromUnits(100),
+					},
+					OccupiedNights: 10,
+				},
+			},
+		},
+		"rooms": {
+			query:        "?rooms=2",
+			expectedCode: http.StatusBadRequest,
+		},
+		"unknown pinned": {
+			query:        "?pinned=Z",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/maximize/frontier"+tt.query, bytes.NewBuffer(payload))
+
+			newHandler(t).HandlerFrontier(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got []FrontierPoint
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandler_HandlerHistogram(t *testing.T) {
+	payload := []byte(`
+		[
+			{
+				"request_id": "bookata_XY123",
+				"check_in": "2020-01-01",
+				"nights": 5,
+				"selling_rate": 200,
+				"margin": 20
+			},
+			{
+				"request_id": "kayete_PP234",
+				"check_in": "2020-01-04",
+				"nights": 4,
+				"selling_rate": 156,
+				"margin": 22
+			}
+		]
+	`)
+
+	tests := map[string]struct {
+		query        string
+		expectedCode int
+		expected     Histogram
+	}{
+		"fixed width": {
+			query:        "?width=1",
+			expectedCode: http.StatusOK,
+			expected: Histogram{
+				Buckets: []Bucket{{From: money.FromUnits(8), To: money.FromUnits(9), Count: 2, Sum: money.MustParse("16.58")}},
+			},
+		},
+		"explicit edges": {
+			query:        "?edges=8.5,9",
+			expectedCode: http.StatusOK,
+			expected: Histogram{
+				Buckets: []Bucket{{From: money.MustParse("8.5"), To: money.FromUnits(9), Count: 1, Sum: money.MustParse("8.58")}},
+				Below:   1,
+			},
+		},
+		"quantiles": {
+			query:        "?quantiles=1",
+			expectedCode: http.StatusOK,
+			expected: Histogram{
+				Buckets: []Bucket{{From: money.FromUnits(8), To: money.MustParse("8.58"), Count: 2, Sum: money.MustParse("16.58")}},
+			},
+		},
+		"horizon": {
+			query:        "?quantiles=1&to=2020-01-05",
+			expectedCode: http.StatusOK,
+			expected: Histogram{
+				Buckets: []Bucket{{From: money.FromUnits(8), To: money.FromUnits(8), Count: 1, Sum: money.FromUnits(8)}},
+			},
+		},
+		"no bins": {
+			expectedCode: http.StatusBadRequest,
+		},
+		"many bins": {
+			query:        "?width=1&quantiles=2",
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid edges": {
+			query:        "?edges=8,nine",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats/histogram"+tt.query, bytes.NewBuffer(payload))
+
+			newHandler(t).HandlerHistogram(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got Histogram
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandler_HandlerCalendar(t *testing.T) {
+	payload := []byte(`
+		[
+			{
+				"request_id": "A",
+				"check_in": "2023-01-01",
+				"nights": 2,
+				"selling_rate": 200,
+				"margin": 10
+			},
+			{
+				"request_id": "B",
+				"check_in": "2023-01-02",
+				"nights": 2,
+				"selling_rate": 300,
+				"margin": 20
+			}
+		]
+	`)
+
+	tests := map[string]struct {
+		query        string
+		expectedCode int
+		expected     []CalendarNight
+	}{
+		"every booking": {
+			expectedCode: http.StatusOK,
+			expected: []CalendarNight{
+				{Date: "2023-01-01", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+				{Date: "2023-01-02", Bookings: 2, Revenue: money.FromUnits(250), Profit: money.FromUnits(40)},
+				{Date: "2023-01-03", Bookings: 1, Revenue: money.FromUnits(150), Profit: money.FromUnits(30)},
+			},
+		},
+		"best combination": {
+			query:        "?maximize=true",
+			expectedCode: http.StatusOK,
+			expected: []CalendarNight{
+				{Date: "2023-01-02", Bookings: 1, Revenue: money.FromUnits(150), Profit: money.FromUnits(30)},
+				{Date: "2023-01-03", Bookings: 1, Revenue: money.FromUnits(150), Profit: money.FromUnits(30)},
+			},
+		},
+		"best combination with pinned": {
+			query:        "?maximize=true&pinned=A",
+			expectedCode: http.StatusOK,
+			expected: []CalendarNight{
+				{Date: "2023-01-01", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+				{Date: "2023-01-02", Bookings: 1, Revenue: money.FromUnits(100), Profit: money.FromUnits(10)},
+			},
+		},
+		"invalid maximize": {
+			query:        "?maximize=maybe",
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid rooms": {
+			query:        "?maximize=true&rooms=0",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/calendar"+tt.query, bytes.NewBuffer(payload))
+
+			newHandler(t).HandlerCalendar(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got []CalendarNight
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandler_Currency(t *testing.T) {
+	stats, err := NewStatsService(WithStatsRates(testRates(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maximize, err := NewMaximizeService(stats, WithRates(testRates(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewHandler(stats, maximize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		handler      *Handler
+		endpoint     string
+		payload      string
+		query        string
+		expectedCode int
+		expected     Reporting
+		expectedAvg  money.Amount
+	}{
+		"stats in the base currency": {
+			handler:      handler,
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "gbp"}]`,
+			expectedCode: http.StatusOK,
+			expected:     Reporting{Currency: "EUR", RatesVersion: "2024-06-01"},
+			expectedAvg:  money.MustParse("12.5"),
+		},
+		"stats in a reporting currency": {
+			handler:      handler,
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+			query:        "?currency=usd",
+			expectedCode: http.StatusOK,
+			expected:     Reporting{Currency: "USD", RatesVersion: "2024-06-01"},
+			expectedAvg:  money.MustParse("12.5"),
+		},
+		"maximize in a reporting currency": {
+			handler:      handler,
+			endpoint:     "/maximize",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+			query:        "?currency=GBP",
+			expectedCode: http.StatusOK,
+			expected:     Reporting{Currency: "GBP", RatesVersion: "2024-06-01"},
+			expectedAvg:  money.FromUnits(8),
+		},
+		"invalid booking currency": {
+			handler:      handler,
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "euros"}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid reporting currency": {
+			handler:      handler,
+			endpoint:     "/maximize",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+			query:        "?currency=euros",
+			expectedCode: http.StatusBadRequest,
+		},
+		"unknown booking currency": {
+			handler:      handler,
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "JPY"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"no rates": {
+			handler:      newHandler(t),
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+			query:        "?currency=GBP",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"no rates for different currencies": {
+			handler:      newHandler(t),
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "GBP"}, {"request_id": "B", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "USD"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"no rates to maximize different currencies": {
+			handler:      newHandler(t),
+			endpoint:     "/maximize",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "GBP"}, {"request_id": "B", "check_in": "2023-01-02", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "USD"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		"no rates for a single currency": {
+			handler:      newHandler(t),
+			endpoint:     "/stats",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "GBP"}, {"request_id": "B", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+			expectedCode: http.StatusOK,
+			expected:     Reporting{Currency: "GBP"},
+			expectedAvg:  money.FromUnits(10),
+		},
+		"no rates to maximize a single currency": {
+			handler:      newHandler(t),
+			endpoint:     "/maximize",
+			payload:      `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "GBP"}, {"request_id": "B", "check_in": "2023-01-02", "nights": 1, "selling_rate": 100, "margin": 10, "currency": "GBP"}]`,
+			expectedCode: http.StatusOK,
+			expected:     Reporting{Currency: "GBP"},
+			expectedAvg:  money.FromUnits(10),
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint+tt.query, bytes.NewBufferString(tt.payload))
+
+			if tt.endpoint == "/maximize" {
+				tt.handler.HandlerMaximize(wr, req)
+			} else {
+				tt.handler.HandlerStats(wr, req)
+			}
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				ProfitPerNight
+				Reporting
+			}
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Reporting != tt.expected {
+				t.Errorf("got: %+v, expected: %+v", got.Reporting, tt.expected)
+			}
+			if got.AvgNight != tt.expectedAvg {
+				t.Errorf("got: %v, expected: %v", got.AvgNight, tt.expectedAvg)
+			}
+		})
+	}
+}
+
+func TestHandler_ConvertedRates(t *testing.T) {
+	// a selling rate at the highest one goes above it in dongs, and out of range in bolívars before their redenomination.
+	table, err := fx.NewTable("2024-06-01", "EUR", map[string]string{"VND": "27000", "VEF": "250000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := fx.NewStaticRates(table)
+	stats, err := NewStatsService(WithStatsRates(rates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maximize, err := NewMaximizeService(stats, WithRates(rates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewHandler(stats, maximize, WithHandlerLogger(testLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := fmt.Sprintf(`{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": %d, "margin": 10}`, maxSellingRate)
+
+	endpoints := map[string]struct {
+		handlerFunc http.HandlerFunc
+		path        string
+		contentType string
+	}{
+		"stats":             {handlerFunc: handler.HandlerStats, path: "/stats?"},
+		"streamed stats":    {handlerFunc: handler.HandlerStats, path: "/stats?", contentType: ndjsonContentType},
+		"histogram":         {handlerFunc: handler.HandlerHistogram, path: "/stats/histogram?quantiles=1&"},
+		"calendar":          {handlerFunc: handler.HandlerCalendar, path: "/calendar?"},
+		"maximize calendar": {handlerFunc: handler.HandlerCalendar, path: "/calendar?maximize=true&"},
+		"maximize":          {handlerFunc: handler.HandlerMaximize, path: "/maximize?"},
+		"frontier":          {handlerFunc: handler.HandlerFrontier, path: "/maximize/frontier?"},
+	}
+	currencies := map[string]string{
+		"VND": "converted_rate_too_high",
+		"VEF": "amount_out_of_range",
+	}
+
+	for name, e := range endpoints {
+		for currency, expectedCode := range currencies {
+			e, currency, expectedCode := e, currency, expectedCode
+			t.Run(name+" in "+currency, func(t *testing.T) {
+				t.Parallel()
+
+				body := "[" + payload + "]"
+				if e.contentType == ndjsonContentType {
+					body = payload + "\n"
+				}
+				wr := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, e.path+"currency="+currency, bytes.NewBufferString(body))
+				if e.contentType != "" {
+					req.Header.Set("Content-Type", e.contentType)
+				}
+
+				e.handlerFunc(wr, req)
+				if wr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("got HTTP status code %d, expected %d", wr.Code, http.StatusUnprocessableEntity)
+				}
+				var got Problem
+				err := json.Unmarshal(wr.Body.Bytes(), &got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Code != expectedCode {
+					t.Errorf("got code %s, expected %s", got.Code, expectedCode)
+				}
+			})
+		}
+	}
+}
+
+func TestHandler_Validation(t *testing.T) {
+	payload := `[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10},
+		{"request_id": "B", "check_in": "2023-01-01", "nights": "one", "selling_rate": 100, "margin": 10}
+	]`
+
+	tests := map[string]struct {
+		query           string
+		expectedCode    int
+		expectedAvg     money.Amount
+		expectedSkipped int
+		expectedIssues  []Issue
+	}{
+		"first error by default": {
+			expectedCode: http.StatusBadRequest,
+		},
+		"lenient": {
+			query:        "?validation=lenient",
+			expectedCode: http.StatusOK,
+			expectedAvg:  money.FromUnits(10),
+		},
+		"lenient counts the unread bookings as skipped": {
+			query:           "?validation=lenient&detailed=true",
+			expectedCode:    http.StatusOK,
+			expectedAvg:     money.FromUnits(10),
+			expectedSkipped: 1,
+		},
+		"strict": {
+			query:        "?validation=strict",
+			expectedCode: http.StatusBadRequest,
+			expectedIssues: []Issue{
+				{Index: 1, Field: "nights", Reason: ReasonWrongType, Message: "booking payload property has the wrong JSON type: nights should be a whole number"},
+			},
+		},
+		"invalid mode": {
+			query:        "?validation=loose",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats"+tt.query, bytes.NewBufferString(payload))
+
+			newHandler(t).HandlerStats(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if tt.expectedIssues != nil {
+				var got struct {
+					Issues []Issue `json:"issues"`
+				}
+				err := json.Unmarshal(wr.Body.Bytes(), &got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got.Issues, tt.expectedIssues) {
+					t.Errorf("got: %+v, expected: %+v", got.Issues, tt.expectedIssues)
+				}
+			}
+
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				ProfitPerNight
+				Skipped int `json:"skipped"`
+			}
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AvgNight != tt.expectedAvg {
+				t.Errorf("got: %v, expected: %v", got.AvgNight, tt.expectedAvg)
+			}
+			if got.Skipped != tt.expectedSkipped {
+				t.Errorf("got %d skipped, expected %d", got.Skipped, tt.expectedSkipped)
+			}
+		})
+	}
+}
+
+func TestHandler_Duplicates(t *testing.T) {
+	payload := `[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10},
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 300, "margin": 10}
+	]`
+
+	tests := map[string]struct {
+		endpoint        string
+		query           string
+		expectedCode    int
+		expectedProblem string
+	}{
+		"stats reject":             {endpoint: "/stats", query: "?duplicates=reject", expectedCode: http.StatusUnprocessableEntity, expectedProblem: "duplicate_request_id"},
+		"stats keep last":          {endpoint: "/stats", query: "?duplicates=last", expectedCode: http.StatusOK},
+		"maximize reject":          {endpoint: "/maximize", query: "?duplicates=reject", expectedCode: http.StatusUnprocessableEntity, expectedProblem: "duplicate_request_id"},
+		"maximize most profitable": {endpoint: "/maximize", query: "?duplicates=most_profitable", expectedCode: http.StatusOK},
+		"invalid policy":           {endpoint: "/maximize", query: "?duplicates=merge", expectedCode: http.StatusBadRequest, expectedProblem: "invalid_duplicates"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint+tt.query, bytes.NewBufferString(payload))
+
+			if tt.endpoint == "/maximize" {
+				newHandler(t).HandlerMaximize(wr, req)
+			} else {
+				newHandler(t).HandlerStats(wr, req)
+			}
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+
+			if tt.expectedProblem != "" {
+				var got Problem
+				err := json.Unmarshal(wr.Body.Bytes(), &got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Code != tt.expectedProblem {
+					t.Errorf("got code %s, expected %s", got.Code, tt.expectedProblem)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_CSV(t *testing.T) {
+	payload := "request_id\tcheck_in\tnights\tselling_rate\tmargin\n" +
+		"A\t2023-01-01\t1\t100\t10\n" +
+		"B\t2023-01-01\t1\t200\t10\n"
+
+	tests := map[string]struct {
+		contentType  string
+		query        string
+		expectedCode int
+		expectedAvg  money.Amount
+	}{
+		"csv":                 {contentType: "text/csv", query: "?delimiter=%09", expectedCode: http.StatusOK, expectedAvg: money.FromUnits(15)},
+		"csv with parameters": {contentType: "text/csv; charset=utf-8; header=present", query: "?delimiter=%09", expectedCode: http.StatusOK, expectedAvg: money.FromUnits(15)},
+		"wrong delimiter":     {contentType: "text/csv", expectedCode: http.StatusBadRequest},
+		"invalid delimiter":   {contentType: "text/csv", query: "?delimiter=%09%09", expectedCode: http.StatusBadRequest},
+		"csv sent as json":    {contentType: "application/json", query: "?delimiter=%09", expectedCode: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats"+tt.query, bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			newHandler(t).HandlerStats(wr, req)
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got ProfitPerNight
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AvgNight != tt.expectedAvg {
+				t.Errorf("got: %v, expected: %v", got.AvgNight, tt.expectedAvg)
+			}
+		})
+	}
+}
+
+func TestHandler_NDJSON(t *testing.T) {
+	payload := `{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}
+{"request_id": "B", "check_in": "2023-01-01", "nights": 1, "selling_rate": 200, "margin": 10}
+`
+
+	tests := map[string]struct {
+		endpoint     string
+		query        string
+		payload      string
+		expectedCode int
+		expectedAvg  money.Amount
+	}{
+		"stats":                  {endpoint: "/stats", payload: payload, expectedCode: http.StatusOK, expectedAvg: money.FromUnits(15)},
+		"stats detailed":         {endpoint: "/stats", query: "?detailed=true", payload: payload, expectedCode: http.StatusBadRequest},
+		"stats with no lines":    {endpoint: "/stats", payload: "\n\n", expectedCode: http.StatusBadRequest},
+		"stats invalid lines":    {endpoint: "/stats", payload: payload + "{}\n", expectedCode: http.StatusBadRequest},
+		"stats lenient":          {endpoint: "/stats", query: "?validation=lenient", payload: payload + "{}\n", expectedCode: http.StatusOK, expectedAvg: money.FromUnits(15)},
+		"maximize":               {endpoint: "/maximize", payload: payload, expectedCode: http.StatusOK, expectedAvg: money.FromUnits(20)},
+		"maximize with no lines": {endpoint: "/maximize", payload: "", expectedCode: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint+tt.query, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/x-ndjson")
+
+			if tt.endpoint == "/maximize" {
+				newHandler(t).HandlerMaximize(wr, req)
+			} else {
+				newHandler(t).HandlerStats(wr, req)
+			}
+			if wr.Code != tt.expectedCode {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, tt.expectedCode)
+			}
+			if wr.Code != http.StatusOK {
+				return
+			}
+
+			var got ProfitPerNight
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AvgNight != tt.expectedAvg {
+				t.Errorf("got: %v, expected: %v", got.AvgNight, tt.expectedAvg)
+			}
+		})
+	}
+}
+
+func TestHandler_BodyTooLarge(t *testing.T) {
+	payloads := map[string]string{
+		"application/json":     `[{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}]`,
+		"text/csv":             "request_id,check_in,nights,selling_rate,margin\nA,2023-01-01,1,100,10\n",
+		"application/x-ndjson": `{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10}` + "\n",
+	}
+
+	for contentType, payload := range payloads {
+		contentType, payload := contentType, payload
+		t.Run(contentType, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats", bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", contentType)
+			req.Body = http.MaxBytesReader(wr, req.Body, 16)
+
+			newHandler(t).HandlerStats(wr, req)
+			if wr.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("got HTTP status code %d, expected %d", wr.Code, http.StatusRequestEntityTooLarge)
+			}
+			if !strings.Contains(wr.Body.String(), `"code":"body_too_large"`) {
+				t.Errorf("got body %s, expected a body_too_large problem", wr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_LargestAmounts(t *testing.T) {
+	var items []string
+	for i, id := range []string{"A", "B", "C", "D", "E"} {
+		items = append(items, fmt.Sprintf(`{"request_id": %q, "check_in": "2023-01-%02d", "nights": 1, "selling_rate": %d, "margin": %d}`, id, 1+2*i, maxSellingRate, maxMargin))
+	}
+	payload := "[" + strings.Join(items, ",") + "]"
+	profit := money.FromUnits(maxSellingRate * maxMargin / 100)
+
+	tests := map[string]struct {
+		endpoint string
+		expected map[string]interface{}
+	}{
+		"stats": {
+			endpoint: "/stats",
+			expected: map[string]interface{}{"avg_night": profit.Float64(), "max_night": profit.Float64()},
+		},
+		"maximize by profit": {
+			endpoint: "/maximize",
+			expected: map[string]interface{}{"total_profit": 5 * profit.Float64(), "avg_night": profit.Float64()},
+		},
+		"maximize by revenue": {
+			endpoint: "/maximize?objective=revenue",
+			expected: map[string]interface{}{"total_profit": 5 * profit.Float64()},
+		},
+		"maximize by average": {
+			endpoint: "/maximize?objective=avg_profit_per_night",
+			expected: map[string]interface{}{"total_profit": 5 * profit.Float64(), "avg_night": profit.Float64()},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint, bytes.NewBufferString(payload))
+			if strings.HasPrefix(tt.endpoint, "/maximize") {
+				newHandler(t).HandlerMaximize(wr, req)
+			} else {
+				newHandler(t).HandlerStats(wr, req)
+			}
+			if wr.Code != http.StatusOK {
+				t.Fatalf("got HTTP status code %d, expected %d: %s", wr.Code, http.StatusOK, wr.Body.String())
+			}
+
+			var got map[string]interface{}
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, expected := range tt.expected {
+				if got[key] != expected {
+					t.Errorf("got %s %v, expected %v", key, got[key], expected)
+				}
+			}
+			if ids, ok := got["request_ids"].([]interface{}); ok && len(ids) != 5 {
+				t.Errorf("got request IDs %v, expected all of them", ids)
 			}
 		})
 	}
@@ -0,0 +1,221 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"sort"
+	"time"
+)
+
+const maxBuckets = 1000
+
+var (
+	errBins           = errors.New("histogram bins should be set by exactly one of width, edges or quantiles")
+	errBinWidth       = errors.New("histogram bin width should be positive")
+	errBinEdges       = errors.New("histogram bin edges should be at least two and increasing")
+	errQuantiles      = fmt.Errorf("histogram quantiles should be between 1 and %d", maxBuckets)
+	errTooManyBuckets = fmt.Errorf("histogram should not have more than %d buckets", maxBuckets)
+)
+
+type binsKind int
+
+const (
+	noBins binsKind = iota
+	widthBins
+	edgeBins
+	quantileBins
+)
+
+// Bins tells how the profit per night values are split into buckets.
+type Bins struct {
+	kind      binsKind
+	width     money.Amount
+	edges     []money.Amount
+	quantiles int
+}
+
+// WidthBins splits the values into buckets of the same width, aligned on multiples of it.
+func WidthBins(width money.Amount) Bins {
+	return Bins{kind: widthBins, width: width}
+}
+
+// EdgeBins splits the values into the buckets between consecutive edges. The values outside the edges are only
+// counted as below or above them.
+func EdgeBins(edges ...money.Amount) Bins {
+	return Bins{kind: edgeBins, edges: edges}
+}
+
+// QuantileBins splits the values into n buckets holding about the same number of values each. Buckets whose edges
+// fall on the same value are merged.
+func QuantileBins(n int) Bins {
+	return Bins{kind: quantileBins, quantiles: n}
+}
+
+func (b Bins) validate() error {
+	switch b.kind {
+	case widthBins:
+		if b.width <= 0 {
+			return errBinWidth
+		}
+	case edgeBins:
+		if len(b.edges) < 2 {
+			return errBinEdges
+		}
+		if len(b.edges) > maxBuckets+1 {
+			return errTooManyBuckets
+		}
+		for i := 1; i < len(b.edges); i++ {
+			if b.edges[i-1] >= b.edges[i] {
+				return errBinEdges
+			}
+		}
+	case quantileBins:
+		if b.quantiles <= 0 || b.quantiles > maxBuckets {
+			return errQuantiles
+		}
+	default:
+		return errBins
+	}
+	return nil
+}
+
+// Histogram holds how many bookings fall in every bucket of profit per night, and how many fall below the first
+// bucket or above the last one.
+type Histogram struct {
+	Buckets []Bucket `json:"buckets"`
+	Below   int      `json:"below"`
+	Above   int      `json:"above"`
+	Reporting
+}
+
+// Bucket holds the bookings with a profit per night from From, included, to To, excluded but for the last bucket,
+// and the sum of their profits per night.
+type Bucket struct {
+	From  money.Amount `json:"from"`
+	To    money.Amount `json:"to"`
+	Count int          `json:"count"`
+	Sum   money.Amount `json:"sum"`
+}
+
+// Histogram bins the profit per night of the valid bookings, under the given options.
+func (s *StatsService) Histogram(bookings []Booking, bins Bins, opts ...StatsOption) (Histogram, error) {
+	err := bins.validate()
+	if err != nil {
+		return Histogram{}, err
+	}
+
+	options, err := s.options(opts)
+	if err != nil {
+		return Histogram{}, err
+	}
+	defer logOperation(options.loggerOrDefault(), "histogram", time.Now())
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return Histogram{}, err
+	}
+
+	values := make([]money.Amount, 0, len(bookings))
+	for _, booking := range bookings {
+		booking, ok := options.horizon.clip(booking)
+		if !ok || !booking.valid() {
+			continue
+		}
+		booking, err = options.conversion.convert(booking)
+		if err != nil {
+			return Histogram{}, err
+		}
+		values = append(values, profitPerNight(booking.SellingRate, booking.Margin, booking.Nights))
+	}
+
+	edges, err := s.edges(values, bins)
+	if err != nil {
+		return Histogram{}, err
+	}
+
+	var (
+		h    = Histogram{Buckets: make([]Bucket, 0, len(edges)), Reporting: options.conversion.reporting()}
+		last = len(edges) - 1
+	)
+	for i := range edges {
+		to := edges[i]
+		if i < last {
+			to = edges[i+1]
+		}
+		h.Buckets = append(h.Buckets, Bucket{From: edges[i], To: to})
+	}
+	if len(edges) > 1 {
+		// the last edge closes the bucket before it instead of opening its own.
+		h.Buckets = h.Buckets[:last]
+	}
+
+	for _, v := range values {
+		i := sort.Search(len(edges), func(i int) bool {
+			return edges[i] > v
+		}) - 1
+
+		switch {
+		case i < 0:
+			h.Below++
+			continue
+		case i == last && len(edges) > 1:
+			if v > edges[last] {
+				h.Above++
+				continue
+			}
+			i--
+		}
+		h.Buckets[i].Count++
+		h.Buckets[i].Sum += v
+	}
+	return h, nil
+}
+
+// edges returns the increasing bucket edges for values, from the first bucket start to the last bucket end. A single
+// edge makes a single bucket holding the values equal to it.
+func (s *StatsService) edges(values []money.Amount, bins Bins) ([]money.Amount, error) {
+	switch {
+	case bins.kind == edgeBins:
+		return bins.edges, nil
+	case len(values) == 0:
+		return nil, nil
+	case bins.kind == quantileBins:
+		sorted := append([]money.Amount(nil), values...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i] < sorted[j]
+		})
+
+		var (
+			n     = int64(bins.quantiles)
+			edges = make([]money.Amount, 0, n+1)
+		)
+		for i := int64(0); i <= n; i++ {
+			var (
+				rank = i * int64(len(sorted)-1) // scaled by n
+				k    = rank / n
+				edge = sorted[k]
+			)
+			if fraction := rank % n; fraction > 0 {
+				edge += (sorted[k+1] - sorted[k]).MulDiv(fraction, n, statsRounding)
+			}
+			if len(edges) == 0 || edge > edges[len(edges)-1] {
+				edges = append(edges, edge)
+			}
+		}
+		return edges, nil
+	default:
+		var (
+			first = floorDiv(int64(s.min(values)), int64(bins.width))
+			last  = floorDiv(int64(s.max(values)), int64(bins.width)) + 1
+		)
+		if last-first > maxBuckets {
+			return nil, errTooManyBuckets
+		}
+
+		edges := make([]money.Amount, 0, last-first+1)
+		for i := first; i <= last; i++ {
+			edges = append(edges, money.Amount(i)*bins.width)
+		}
+		return edges, nil
+	}
+}
@@ -0,0 +1,129 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+)
+
+func TestStatsService_Histogram(t *testing.T) {
+	// one booking per profit per night from 1 to 10, and an invalid one.
+	var bookings []Booking
+	for margin := int32(1); margin <= 10; margin++ {
+		bookings = append(bookings, Booking{
+			RequestID:   "acme_AAAA",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      1,
+			SellingRate: money.FromUnits(100),
+			Margin:      margin,
+		})
+	}
+	bookings = append(bookings, Booking{RequestID: "acme_BBBB", SellingRate: money.FromUnits(100), Margin: 1})
+
+	tests := map[string]struct {
+		bookings    []Booking
+		bins        Bins
+		expected    Histogram
+		expectedErr error
+	}{
+		"fixed width": {
+			bookings: bookings,
+			bins:     WidthBins(money.FromUnits(3)),
+			expected: Histogram{
+				Buckets: []Bucket{
+					{From: money.FromUnits(0), To: money.FromUnits(3), Count: 2, Sum: money.FromUnits(3)},
+					{From: money.FromUnits(3), To: money.FromUnits(6), Count: 3, Sum: money.FromUnits(12)},
+					{From: money.FromUnits(6), To: money.FromUnits(9), Count: 3, Sum: money.FromUnits(21)},
+					{From: money.FromUnits(9), To: money.FromUnits(12), Count: 2, Sum: money.FromUnits(19)},
+				},
+			},
+		},
+		"explicit edges": {
+			bookings: bookings,
+			bins:     EdgeBins(money.FromUnits(2), money.FromUnits(5), money.FromUnits(8)),
+			expected: Histogram{
+				Buckets: []Bucket{
+					{From: money.FromUnits(2), To: money.FromUnits(5), Count: 3, Sum: money.FromUnits(9)},
+					{From: money.FromUnits(5), To: money.FromUnits(8), Count: 4, Sum: money.FromUnits(26)},
+				},
+				Below: 1,
+				Above: 2,
+			},
+		},
+		"quantiles": {
+			bookings: bookings,
+			bins:     QuantileBins(2),
+			expected: Histogram{
+				Buckets: []Bucket{
+					{From: money.FromUnits(1), To: money.MustParse("5.5"), Count: 5, Sum: money.FromUnits(15)},
+					{From: money.MustParse("5.5"), To: money.FromUnits(10), Count: 5, Sum: money.FromUnits(40)},
+				},
+			},
+		},
+		"quantiles of equal values": {
+			bookings: []Booking{bookings[3], bookings[3], bookings[3]},
+			bins:     QuantileBins(4),
+			expected: Histogram{
+				Buckets: []Bucket{
+					{From: money.FromUnits(4), To: money.FromUnits(4), Count: 3, Sum: money.FromUnits(12)},
+				},
+			},
+		},
+		"no valid bookings": {
+			bookings: bookings[10:],
+			bins:     WidthBins(money.FromUnits(3)),
+			expected: Histogram{Buckets: []Bucket{}},
+		},
+		"no bins": {
+			bookings:    bookings,
+			expectedErr: errBins,
+		},
+		"zero width": {
+			bookings:    bookings,
+			bins:        WidthBins(0),
+			expectedErr: errBinWidth,
+		},
+		"negative width": {
+			bookings:    bookings,
+			bins:        WidthBins(money.FromMinor(-1)),
+			expectedErr: errBinWidth,
+		},
+		"single edge": {
+			bookings:    bookings,
+			bins:        EdgeBins(money.FromUnits(1)),
+			expectedErr: errBinEdges,
+		},
+		"decreasing edges": {
+			bookings:    bookings,
+			bins:        EdgeBins(money.FromUnits(2), money.FromUnits(1)),
+			expectedErr: errBinEdges,
+		},
+		"negative quantiles": {
+			bookings:    bookings,
+			bins:        QuantileBins(-1),
+			expectedErr: errQuantiles,
+		},
+		"too many buckets": {
+			bookings:    append([]Booking{{RequestID: "acme_CCCC", Nights: 1, SellingRate: money.FromUnits(1000), Margin: 10}}, bookings...),
+			bins:        WidthBins(money.FromMinor(1)),
+			expectedErr: errTooManyBuckets,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newStatsService(t).Histogram(tt.bookings, tt.bins)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
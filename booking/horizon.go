@@ -0,0 +1,89 @@
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"time"
+)
+
+// HorizonMode tells what happens to the stays that straddle a planning horizon boundary.
+type HorizonMode string
+
+const (
+	// HorizonExclude leaves out the stays with any night outside the horizon.
+	HorizonExclude HorizonMode = "exclude"
+	// HorizonInclude keeps the stays with any night inside the horizon whole.
+	HorizonInclude HorizonMode = "include"
+	// HorizonProrate clips the stays to their nights inside the horizon, scaling the selling rate, and so the profit,
+	// to those nights and rounding it down to the cent.
+	HorizonProrate HorizonMode = "prorate"
+)
+
+var (
+	errHorizon              = errors.New("horizon from should not be after to")
+	errHorizonMode          = errors.New("horizon mode should be exclude, include or prorate")
+	errPinnedOutsideHorizon = errors.New("pinned booking is outside the planning horizon")
+)
+
+// horizon is the range of nights, from and to included, bookings are planned on. A zero bound leaves that side open.
+type horizon struct {
+	from, to time.Time
+	mode     HorizonMode
+}
+
+// newHorizon returns the horizon between from and to, excluding the stays that straddle it when mode is empty.
+func newHorizon(from, to time.Time, mode HorizonMode) (*horizon, error) {
+	if !from.IsZero() && !to.IsZero() && dayNumber(from) > dayNumber(to) {
+		return nil, errHorizon
+	}
+
+	switch mode {
+	case "":
+		mode = HorizonExclude
+	case HorizonExclude, HorizonInclude, HorizonProrate:
+	default:
+		return nil, errHorizonMode
+	}
+
+	return &horizon{from: from, to: to, mode: mode}, nil
+}
+
+// clip returns the booking as planned within the horizon, and false when it's left out. Invalid bookings and any
+// booking on a nil horizon are returned as they are.
+func (h *horizon) clip(b Booking) (Booking, bool) {
+	if h == nil || !b.valid() {
+		return b, true
+	}
+
+	var (
+		start  = dayNumber(b.CheckIn)
+		end    = start + int64(b.Nights)
+		first  = start
+		last   = end
+		inside int64
+	)
+	if !h.from.IsZero() && dayNumber(h.from) > first {
+		first = dayNumber(h.from)
+	}
+	if !h.to.IsZero() && dayNumber(h.to)+1 < last {
+		last = dayNumber(h.to) + 1
+	}
+	if inside = last - first; inside <= 0 {
+		return Booking{}, false
+	}
+	if inside == int64(b.Nights) {
+		return b, true
+	}
+
+	switch h.mode {
+	case HorizonInclude:
+		return b, true
+	case HorizonProrate:
+		b.CheckIn = b.CheckIn.AddDate(0, 0, int(first-start))
+		b.SellingRate = b.SellingRate.MulDiv(inside, int64(b.Nights), money.Down)
+		b.Nights = int32(inside)
+		return b, true
+	default:
+		return Booking{}, false
+	}
+}
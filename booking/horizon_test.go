@@ -0,0 +1,303 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHorizon_Clip(t *testing.T) {
+	b := Booking{
+		RequestID:   "A",
+		CheckIn:     parse("2023-01-10"),
+		Nights:      4,
+		SellingRate: money.FromUnits(400),
+		Margin:      10,
+	}
+
+	tests := map[string]struct {
+		from, to       string
+		mode           HorizonMode
+		booking        Booking
+		expected       Booking
+		expectedWithin bool
+	}{
+		"inside": {
+			from:           "2023-01-01",
+			to:             "2023-01-31",
+			booking:        b,
+			expected:       b,
+			expectedWithin: true,
+		},
+		"last night on the bound": {
+			from:           "2023-01-10",
+			to:             "2023-01-13",
+			booking:        b,
+			expected:       b,
+			expectedWithin: true,
+		},
+		"before": {
+			to:      "2023-01-09",
+			booking: b,
+		},
+		"after": {
+			from:    "2023-01-14",
+			booking: b,
+		},
+		"straddling excluded by default": {
+			from:    "2023-01-12",
+			booking: b,
+		},
+		"straddling included": {
+			from:           "2023-01-12",
+			mode:           HorizonInclude,
+			booking:        b,
+			expected:       b,
+			expectedWithin: true,
+		},
+		"straddling from prorated": {
+			from:    "2023-01-12",
+			mode:    HorizonProrate,
+			booking: b,
+			expected: Booking{
+				RequestID:   "A",
+				CheckIn:     parse("2023-01-12"),
+				Nights:      2,
+				SellingRate: money.FromUnits(200),
+				Margin:      10,
+			},
+			expectedWithin: true,
+		},
+		"straddling to prorated": {
+			to:      "2023-01-10",
+			mode:    HorizonProrate,
+			booking: b,
+			expected: Booking{
+				RequestID:   "A",
+				CheckIn:     parse("2023-01-10"),
+				Nights:      1,
+				SellingRate: money.FromUnits(100),
+				Margin:      10,
+			},
+			expectedWithin: true,
+		},
+		"invalid booking": {
+			to:             "2023-01-01",
+			booking:        Booking{RequestID: "B", CheckIn: parse("2023-01-10")},
+			expected:       Booking{RequestID: "B", CheckIn: parse("2023-01-10")},
+			expectedWithin: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var from, to time.Time
+			if tt.from != "" {
+				from = parse(tt.from)
+			}
+			if tt.to != "" {
+				to = parse(tt.to)
+			}
+
+			h, err := newHorizon(from, to, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, within := h.clip(tt.booking)
+			if within != tt.expectedWithin {
+				t.Fatalf("got within: %t, expected: %t", within, tt.expectedWithin)
+			}
+			if within && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewHorizon(t *testing.T) {
+	tests := map[string]struct {
+		from, to    time.Time
+		mode        HorizonMode
+		expectedErr error
+	}{
+		"open": {},
+		"single night": {
+			from: parse("2023-01-01"),
+			to:   parse("2023-01-01"),
+			mode: HorizonProrate,
+		},
+		"from after to": {
+			from:        parse("2023-01-02"),
+			to:          parse("2023-01-01"),
+			expectedErr: errHorizon,
+		},
+		"unknown mode": {
+			mode:        "clip",
+			expectedErr: errHorizonMode,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := newHorizon(tt.from, tt.to, tt.mode)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestStatsService_SummarizeWithHorizon(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "X",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      2,
+			SellingRate: money.FromUnits(100),
+			Margin:      10,
+		},
+		{
+			RequestID:   "Y",
+			CheckIn:     parse("2023-01-10"),
+			Nights:      4,
+			SellingRate: money.FromUnits(400),
+			Margin:      20,
+		},
+	}
+
+	tests := map[string]struct {
+		opts        []StatsOption
+		expected    ProfitPerNight
+		expectedErr error
+	}{
+		"no horizon": {
+			expected: ProfitPerNight{AvgNight: money.MustParse("12.5"), MinNight: money.FromUnits(5), MaxNight: money.FromUnits(20)},
+		},
+		"from": {
+			opts:     []StatsOption{WithStatsHorizon(parse("2023-01-05"), time.Time{}, "")},
+			expected: ProfitPerNight{AvgNight: money.FromUnits(20), MinNight: money.FromUnits(20), MaxNight: money.FromUnits(20)},
+		},
+		"straddling excluded": {
+			opts: []StatsOption{WithStatsHorizon(time.Time{}, parse("2023-01-01"), HorizonExclude)},
+		},
+		"straddling prorated": {
+			opts:     []StatsOption{WithStatsHorizon(time.Time{}, parse("2023-01-01"), HorizonProrate)},
+			expected: ProfitPerNight{AvgNight: money.FromUnits(5), MinNight: money.FromUnits(5), MaxNight: money.FromUnits(5)},
+		},
+		"from after to": {
+			opts:        []StatsOption{WithStatsHorizon(parse("2023-01-05"), parse("2023-01-01"), "")},
+			expectedErr: errHorizon,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newStatsService(t).Summarize(bookings, tt.opts...)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got.ProfitPerNight != tt.expected {
+				t.Errorf("got: %v, expected: %v", got.ProfitPerNight, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsWithHorizon(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2018-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2018-01-06"),
+			Nights:      10,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      10,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+	}
+
+	var (
+		from = parse("2018-01-05")
+		to   = parse("2018-01-20")
+	)
+
+	tests := map[string]struct {
+		opts           []MaximizeOption
+		expectedIDS    []string
+		expectedProfit money.Amount
+		expectedErr    error
+	}{
+		"straddling excluded": {
+			opts:           []MaximizeOption{WithHorizon(from, to, HorizonExclude)},
+			expectedIDS:    []string{"B"},
+			expectedProfit: money.FromUnits(70),
+		},
+		"straddling included": {
+			opts:           []MaximizeOption{WithHorizon(from, to, HorizonInclude)},
+			expectedIDS:    []string{"A", "C"},
+			expectedProfit: money.FromUnits(140),
+		},
+		"straddling prorated": {
+			opts:           []MaximizeOption{WithHorizon(from, to, HorizonProrate)},
+			expectedIDS:    []string{"A", "C"},
+			expectedProfit: money.FromUnits(96),
+		},
+		"open to": {
+			opts:           []MaximizeOption{WithHorizon(parse("2018-01-12"), time.Time{}, "")},
+			expectedIDS:    []string{"C"},
+			expectedProfit: money.FromUnits(40),
+		},
+		"pinned outside": {
+			opts:        []MaximizeOption{WithHorizon(time.Time{}, parse("2018-01-04"), ""), WithPinned("B")},
+			expectedErr: errPinnedOutsideHorizon,
+		},
+		"unknown mode": {
+			opts:        []MaximizeOption{WithHorizon(from, to, "clip")},
+			expectedErr: errHorizonMode,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(bookings, tt.opts...)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expectedIDS) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expectedIDS)
+			}
+			if got.TotalProfit != tt.expectedProfit {
+				t.Errorf("got: %v, expected: %v", got.TotalProfit, tt.expectedProfit)
+			}
+		})
+	}
+}
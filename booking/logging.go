@@ -0,0 +1,65 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var errLogger = errors.New("logger should not be nil")
+
+type scopeKey struct{}
+
+// requestScope is the logging scope of a request: its logger, with the request ID and the endpoint, then the bookings
+// once decoded, attached to every line, the request ID, sent by the client or generated, and when it started.
+type requestScope struct {
+	logger    *slog.Logger
+	requestID string
+	start     time.Time
+}
+
+// begin returns req along with the scope of its handling by endpoint.
+func (h *Handler) begin(req *http.Request, endpoint string) (*http.Request, *requestScope) {
+	s := h.newScope(req, endpoint)
+	s.logger.Debug("processing request")
+	return req.WithContext(context.WithValue(req.Context(), scopeKey{}, s)), s
+}
+
+// end closes the body of req, once handled, and logs how long it took. It logs at debug level, the access log of the
+// server being the line of every request.
+func (h *Handler) end(req *http.Request, s *requestScope) {
+	err := req.Body.Close()
+	if err != nil {
+		s.logger.Warn("failed to close request body", "error", err)
+	}
+	s.logger.Debug("request processed", "duration", time.Since(s.start))
+}
+
+// scope returns the scope of req, or a new one when no endpoint began it.
+func (h *Handler) scope(req *http.Request) *requestScope {
+	if s, ok := req.Context().Value(scopeKey{}).(*requestScope); ok {
+		return s
+	}
+	return h.newScope(req, req.URL.Path)
+}
+
+func (h *Handler) newScope(req *http.Request, endpoint string) *requestScope {
+	id := requestID(req)
+	return &requestScope{
+		logger:    h.logger.With("request_id", id, "endpoint", endpoint),
+		requestID: id,
+		start:     time.Now(),
+	}
+}
+
+// counted attaches the number of bookings of the request to the later lines.
+func (s *requestScope) counted(bookings int) {
+	s.logger = s.logger.With("bookings", bookings)
+}
+
+// logOperation logs the end of an operation of the services, and how long it took since start.
+func logOperation(logger *slog.Logger, operation string, start time.Time) {
+	logger.Debug("operation done", "operation", operation, "duration", time.Since(start))
+}
@@ -0,0 +1,129 @@
+//go:build unit
+
+package booking
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_Logging(t *testing.T) {
+	payload := `[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 1, "selling_rate": 100, "margin": 10},
+		{"request_id": "B", "check_in": "2023-01-02", "nights": 1, "selling_rate": 200, "margin": 10}
+	]`
+
+	tests := map[string]struct {
+		endpoint         string
+		payload          string
+		requestID        string
+		expectedMessages []string
+		expectedBookings float64
+	}{
+		"stats": {
+			endpoint:         "/stats",
+			payload:          payload,
+			requestID:        "client-42",
+			expectedMessages: []string{"processing request", "operation done", "request processed"},
+			expectedBookings: 2,
+		},
+		"maximize": {
+			endpoint:         "/maximize",
+			payload:          payload,
+			requestID:        "client-42",
+			expectedMessages: []string{"processing request", "operation done", "request processed"},
+			expectedBookings: 2,
+		},
+		"rejected": {
+			endpoint:         "/stats",
+			payload:          "[]",
+			expectedMessages: []string{"processing request", "request rejected", "request processed"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			handler := newHandler(t, WithHandlerLogger(logger))
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint, bytes.NewBufferString(tt.payload))
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			if tt.endpoint == "/maximize" {
+				handler.HandlerMaximize(wr, req)
+			} else {
+				handler.HandlerStats(wr, req)
+			}
+
+			// the request ID of the lines is the one sent, or the one answered with the problem when generated.
+			requestID := req.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = wr.Header().Get(RequestIDHeader)
+			}
+			if tt.requestID != "" && requestID != tt.requestID {
+				t.Errorf("got request ID %s, expected %s", requestID, tt.requestID)
+			}
+
+			var messages []string
+			d := json.NewDecoder(&logs)
+			for d.More() {
+				var line map[string]interface{}
+				err := d.Decode(&line)
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages = append(messages, line["msg"].(string))
+
+				if line["request_id"] != requestID {
+					t.Errorf("got request ID %v in %q, expected %s", line["request_id"], line["msg"], requestID)
+				}
+				if line["endpoint"] != tt.endpoint[1:] {
+					t.Errorf("got endpoint %v in %q, expected %s", line["endpoint"], line["msg"], tt.endpoint[1:])
+				}
+				if line["msg"] == "request processed" {
+					if _, ok := line["duration"]; !ok {
+						t.Errorf("got no duration in %q", line["msg"])
+					}
+					if tt.expectedBookings != 0 && line["bookings"] != tt.expectedBookings {
+						t.Errorf("got %v bookings in %q, expected %v", line["bookings"], line["msg"], tt.expectedBookings)
+					}
+				}
+			}
+			if len(messages) != len(tt.expectedMessages) {
+				t.Fatalf("got messages %q, expected %q", messages, tt.expectedMessages)
+			}
+			for i := range messages {
+				if messages[i] != tt.expectedMessages[i] {
+					t.Errorf("got messages %q, expected %q", messages, tt.expectedMessages)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerOptions(t *testing.T) {
+	_, err := NewStatsService(WithStatsLogger(nil))
+	if !errors.Is(err, errLogger) {
+		t.Errorf("got error: %v, expected: %v", err, errLogger)
+	}
+	_, err = NewMaximizeService(newStatsService(t), WithLogger(nil))
+	if !errors.Is(err, errLogger) {
+		t.Errorf("got error: %v, expected: %v", err, errLogger)
+	}
+	_, err = NewHandler(newStatsService(t), newMaximizeService(t), WithHandlerLogger(nil))
+	if !errors.Is(err, errLogger) {
+		t.Errorf("got error: %v, expected: %v", err, errLogger)
+	}
+}
@@ -0,0 +1,194 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/fx"
+	"log/slog"
+	"time"
+)
+
+const (
+	maxTop = 100
+	// maxRooms caps the rooms of a request, as every room is listed in the response.
+	maxRooms = 10_000
+
+	// defaultBufferNights keeps the check-out day blocked, so the next stay can check in the day after.
+	defaultBufferNights = 1
+)
+
+var (
+	errRooms        = fmt.Errorf("rooms should be between 1 and %d", maxRooms)
+	errBufferNights = errors.New("buffer nights should not be negative")
+	errTop          = fmt.Errorf("top should be between 1 and %d", maxTop)
+
+	errExplainTop = errors.New("explain is only available for the best combination")
+
+	errPinnedExcluded   = errors.New("request_id cannot be pinned and excluded at once")
+	errUnknownRequestID = errors.New("request_id not found in the payload")
+	errPinnedInvalid    = errors.New("pinned booking is not valid")
+	errPinnedOverlap    = errors.New("pinned bookings overlap each other beyond the available rooms")
+)
+
+type maximizeOptions struct {
+	rooms        *int
+	bufferNights *int
+	objective    *Objective
+	horizon      *horizon
+	explain      bool
+	pinned       map[string]struct{}
+	excluded     map[string]struct{}
+	rates        fx.RateProvider
+	currency     string
+	conversion   *conversion
+	duplicates   DuplicatePolicy
+	logger       *slog.Logger
+}
+
+type MaximizeOption func(options *maximizeOptions) error
+
+// WithRooms sets how many identical units can be sold at once, so up to rooms bookings may overlap on any night.
+func WithRooms(rooms int) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if rooms <= 0 || rooms > maxRooms {
+			return errRooms
+		}
+		options.rooms = &rooms
+		return nil
+	}
+}
+
+// WithBufferNights sets how many nights a unit stays free between a check-out and the next check-in, for cleaning or
+// maintenance. Zero allows same-day turnover, checking in on the day the previous guest checks out.
+func WithBufferNights(nights int) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if nights < 0 {
+			return errBufferNights
+		}
+		options.bufferNights = &nights
+		return nil
+	}
+}
+
+// WithSameDayTurnover lets a booking check in on the day the previous one checks out.
+func WithSameDayTurnover() MaximizeOption {
+	return WithBufferNights(0)
+}
+
+// WithObjective sets what the best combination maximises, the total profit by default.
+func WithObjective(objective Objective) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if !objective.valid() {
+			return errObjective
+		}
+		options.objective = &objective
+		return nil
+	}
+}
+
+// WithHorizon only plans the nights from and to, both included, a zero bound leaving that side open. Mode tells what
+// to do with the stays straddling a bound, leaving them out by default.
+func WithHorizon(from, to time.Time, mode HorizonMode) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		h, err := newHorizon(from, to, mode)
+		if err != nil {
+			return err
+		}
+		options.horizon = h
+		return nil
+	}
+}
+
+// WithExplanation reports, next to the best combination, why every booking was selected, rejected or found invalid.
+func WithExplanation() MaximizeOption {
+	return func(options *maximizeOptions) error {
+		options.explain = true
+		return nil
+	}
+}
+
+// WithPinned forces the bookings with the given request IDs into every combination, the rest being optimised
+// around them.
+func WithPinned(ids ...string) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		options.pinned = union(options.pinned, ids)
+		return nil
+	}
+}
+
+// WithExcluded leaves the bookings with the given request IDs out of every combination.
+func WithExcluded(ids ...string) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		options.excluded = union(options.excluded, ids)
+		return nil
+	}
+}
+
+// WithRates normalises every booking to the reporting currency with the exchange rates of provider, before
+// computing any profit.
+func WithRates(provider fx.RateProvider) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		options.rates = provider
+		return nil
+	}
+}
+
+// WithCurrency sets the currency the combinations are reported in, the base currency of the exchange rates by
+// default.
+func WithCurrency(currency string) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if !fx.ValidCurrency(currency) {
+			return fx.ErrCurrency
+		}
+		options.currency = currency
+		return nil
+	}
+}
+
+// WithDuplicates sets which booking to take into account when several share a request ID, all of them by default.
+func WithDuplicates(policy DuplicatePolicy) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if !policy.valid() {
+			return errDuplicatePolicy
+		}
+		options.duplicates = policy
+		return nil
+	}
+}
+
+// WithLogger sets the logger the operations are logged with, at debug level, slog.Default() by default.
+func WithLogger(logger *slog.Logger) MaximizeOption {
+	return func(options *maximizeOptions) error {
+		if logger == nil {
+			return errLogger
+		}
+		options.logger = logger
+		return nil
+	}
+}
+
+// union returns a new set holding the items of set and ids.
+func union(set map[string]struct{}, ids []string) map[string]struct{} {
+	u := make(map[string]struct{}, len(set)+len(ids))
+	for id := range set {
+		u[id] = struct{}{}
+	}
+	for _, id := range ids {
+		u[id] = struct{}{}
+	}
+	return u
+}
+
+func (o maximizeOptions) objectiveOrDefault() Objective {
+	if o.objective != nil {
+		return *o.objective
+	}
+	return ObjectiveProfit
+}
+
+func (o maximizeOptions) loggerOrDefault() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
@@ -1,71 +1,257 @@
 package booking
 
 import (
+	"fmt"
+	"github.com/xsolrac87/booking/money"
 	"sort"
+	"time"
 )
 
 type MaximizeService struct {
 	statsService *StatsService
+	defaults     []MaximizeOption
 }
 
-func NewMaximizeService(svc *StatsService) (*MaximizeService, error) {
+// NewMaximizeService returns a service applying opts as defaults to every request, before the request's own options.
+func NewMaximizeService(svc *StatsService, opts ...MaximizeOption) (*MaximizeService, error) {
+	var options maximizeOptions
+	for _, opt := range opts {
+		err := opt(&options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &MaximizeService{
 		statsService: svc,
+		defaults:     opts,
 	}, nil
 }
 
-func (m *MaximizeService) MaximTotalProfits(bookings []Booking) MaximizeProfit {
-	m.sortBookingsByCheckIn(bookings)
+// MaximTotalProfits returns the combination of bookings with the highest total profit, or any other objective set,
+// such that, on any night, no more bookings overlap than there are rooms (a single one by default). When rooms are
+// set, the response also tells which request IDs go to which room.
+func (m *MaximizeService) MaximTotalProfits(bookings []Booking, opts ...MaximizeOption) (MaximizeProfit, error) {
+	s, err := m.selection(bookings, opts...)
+	if err != nil {
+		return MaximizeProfit{}, err
+	}
+	return s.MaximizeProfit, nil
+}
+
+// selection returns the best combination of bookings along with the bookings it selects, as the solver took them:
+// the one kept of every duplicated request ID, clipped to the horizon and in the reporting currency.
+func (m *MaximizeService) selection(bookings []Booking, opts ...MaximizeOption) (selection, error) {
+	options, err := m.options(opts)
+	if err != nil {
+		return selection{}, err
+	}
+	defer logOperation(options.loggerOrDefault(), "maximize", time.Now())
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return selection{}, err
+	}
+
+	p, err := m.problem(bookings, options)
+	if err != nil {
+		return selection{}, err
+	}
+
+	best, err := p.best()
+	if err != nil {
+		return selection{}, err
+	}
+
+	s := selection{MaximizeProfit: m.calculateSchedule(p, best, options)}
+	if options.explain {
+		s.Explanation = m.explain(bookings, p, best)
+	}
+	for _, selected := range p.selected(best) {
+		s.bookings = append(s.bookings, selected.booking)
+	}
+	return s, nil
+}
+
+// TopTotalProfits returns up to k distinct combinations of bookings ranked by the objective, the best one first,
+// under the same rules as MaximTotalProfits.
+func (m *MaximizeService) TopTotalProfits(bookings []Booking, k int, opts ...MaximizeOption) ([]MaximizeProfit, error) {
+	r, err := m.ranking(bookings, k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MaximizeProfit, 0, len(r))
+	for _, s := range r {
+		results = append(results, s.MaximizeProfit)
+	}
+	return results, nil
+}
+
+// ranking returns the top combinations like TopTotalProfits, along with the bookings each of them selects.
+func (m *MaximizeService) ranking(bookings []Booking, k int, opts ...MaximizeOption) (ranking, error) {
+	if k <= 0 || k > maxTop {
+		return nil, errTop
+	}
+
+	options, err := m.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer logOperation(options.loggerOrDefault(), "top", time.Now())
+	if options.explain {
+		return nil, errExplainTop
+	}
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := m.problem(bookings, options)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.best()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := p.top(k)
+	r := make(ranking, 0, len(schedules))
+	for _, indexes := range schedules {
+		s := selection{MaximizeProfit: m.calculateSchedule(p, indexes, options)}
+		for _, selected := range p.selected(indexes) {
+			s.bookings = append(s.bookings, selected.booking)
+		}
+		r = append(r, s)
+	}
+	return r, nil
+}
+
+func (m *MaximizeService) options(opts []MaximizeOption) (maximizeOptions, error) {
+	var options maximizeOptions
+	for _, opt := range append(append([]MaximizeOption(nil), m.defaults...), opts...) {
+		err := opt(&options)
+		if err != nil {
+			return maximizeOptions{}, err
+		}
+	}
+
+	for id := range options.pinned {
+		if _, ok := options.excluded[id]; ok {
+			return maximizeOptions{}, fmt.Errorf("%w: %s", errPinnedExcluded, id)
+		}
+	}
+
+	conversion, err := newConversion(options.rates, options.currency)
+	if err != nil {
+		return maximizeOptions{}, err
+	}
+	options.conversion = conversion
+	return options, nil
+}
+
+func (m *MaximizeService) problem(bookings []Booking, options maximizeOptions) (problem, error) {
+	rooms := 1
+	if options.rooms != nil {
+		rooms = *options.rooms
+	}
+
+	bufferNights := defaultBufferNights
+	if options.bufferNights != nil {
+		bufferNights = *options.bufferNights
+	}
 
 	var (
-		combinations          = make(map[string][]Booking)
-		checked               = make(map[string]struct{})
-		bestIndividualBooking MaximizeProfit
-		bestBookingCombo      MaximizeProfit
+		p = problem{
+			rooms:      rooms,
+			objective:  options.objectiveOrDefault(),
+			horizon:    options.horizon,
+			conversion: options.conversion,
+		}
+		found = make(map[string]struct{}, len(options.pinned)+len(options.excluded))
 	)
-
-	for i, booking := range bookings {
-		individual := m.calculateIndividual(booking)
-		if m.hasBetterTotalProfit(bestIndividualBooking, individual) {
-			bestIndividualBooking = individual
+	for _, b := range bookings {
+		_, pinned := options.pinned[b.RequestID]
+		_, excluded := options.excluded[b.RequestID]
+		if pinned || excluded {
+			found[b.RequestID] = struct{}{}
 		}
 
-		if _, ok := checked[booking.RequestID]; ok {
+		if !b.valid() {
+			if pinned {
+				return problem{}, fmt.Errorf("%w: %s", errPinnedInvalid, b.RequestID)
+			}
 			continue
 		}
 
-		combinations[booking.RequestID] = append(combinations[booking.RequestID], booking)
-	Combo:
-		for j := len(bookings[i+1:]); j > i; j-- {
-			b := bookings[j]
-			if !m.noOverlap(booking, b) {
-				break Combo
+		within, ok := p.horizon.clip(b)
+		if !ok {
+			if pinned {
+				return problem{}, fmt.Errorf("%w: %s", errPinnedOutsideHorizon, b.RequestID)
 			}
-			combinations[booking.RequestID] = append(combinations[booking.RequestID], b)
-			checked[b.RequestID] = struct{}{}
+			continue
 		}
+		within, err := p.conversion.convert(within)
+		if err != nil {
+			return problem{}, err
+		}
+
+		s := newStay(within, bufferNights)
+		s.weight[objectiveWeight] = p.objective.bookingValue(within)
+		p.stays = append(p.stays, s)
+		p.pinned = append(p.pinned, pinned)
+		p.excluded = append(p.excluded, excluded)
+	}
 
-		if len(combinations[booking.RequestID]) > 1 {
-			combo := m.calculateCombination(combinations[booking.RequestID]...)
-			if m.hasBetterTotalProfit(bestBookingCombo, combo) {
-				bestBookingCombo = combo
+	for _, ids := range []map[string]struct{}{options.pinned, options.excluded} {
+		for _, id := range sortedKeys(ids) {
+			if _, ok := found[id]; !ok {
+				return problem{}, fmt.Errorf("%w: %s", errUnknownRequestID, id)
 			}
 		}
 	}
+	// past one room per stay, the rooms would stay empty.
+	p.rooms = max(1, min(p.rooms, len(p.stays)))
+	return p, p.checkRange()
+}
 
-	return m.bestOne(bestIndividualBooking, bestBookingCombo)
+// calculateSchedule builds the response for the stays of p at indexes, listed by check-in.
+func (m *MaximizeService) calculateSchedule(p problem, indexes []int, options maximizeOptions) MaximizeProfit {
+	selected := p.selected(indexes)
+	combination := make([]Booking, 0, len(selected))
+	for _, s := range selected {
+		combination = append(combination, s.booking)
+	}
+
+	result := m.calculateCombination(combination...)
+	result.Objective = p.objective
+	result.ObjectiveValue = p.objective.value(result, combination)
+	result.Reporting = p.conversion.reporting()
+	if options.rooms != nil {
+		result.Rooms = assignRooms(selected, p.rooms)
+	}
+	return result
 }
 
-func (m *MaximizeService) calculateIndividual(b Booking) MaximizeProfit {
-	p := profit(b.SellingRate, b.Margin)
-	perNight := m.statsService.ProfitPerNight([]Booking{b})
-	return NewMaximizeProfit([]string{b.RequestID}, p, perNight)
+// selected returns the stays of p at indexes, listed by check-in.
+func (p problem) selected(indexes []int) []stay {
+	indexes = append([]int(nil), indexes...)
+	sort.Ints(indexes)
+
+	selected := make([]stay, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, p.stays[i])
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].start < selected[j].start
+	})
+	return selected
 }
 
 func (m *MaximizeService) calculateCombination(bookings ...Booking) MaximizeProfit {
 	var (
-		requestID   []string
-		totalProfit int32
+		requestID   = make([]string, 0, len(bookings))
+		totalProfit money.Amount
 	)
 
 	for _, b := range bookings {
@@ -77,24 +263,11 @@ func (m *MaximizeService) calculateCombination(bookings ...Booking) MaximizeProf
 	return NewMaximizeProfit(requestID, totalProfit, perNight)
 }
 
-func (m *MaximizeService) hasBetterTotalProfit(a, b MaximizeProfit) bool {
-	return a.TotalProfit == 0 || a.TotalProfit < b.TotalProfit
-}
-
-func (m *MaximizeService) sortBookingsByCheckIn(bookings []Booking) {
-	sort.Slice(bookings, func(i, j int) bool {
-		return bookings[i].CheckIn.Before(bookings[j].CheckIn)
-	})
-}
-
-func (m *MaximizeService) noOverlap(a, b Booking) bool {
-	checkOut := a.CheckIn.AddDate(0, 0, int(a.Nights))
-	return b.CheckIn.After(checkOut)
-}
-
-func (m *MaximizeService) bestOne(a, b MaximizeProfit) MaximizeProfit {
-	if a.TotalProfit > b.TotalProfit {
-		return a
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
 	}
-	return b
+	sort.Strings(keys)
+	return keys
 }
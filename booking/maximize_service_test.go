@@ -3,8 +3,13 @@
 package booking
 
 import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
 	"github.com/xsolrac87/booking/timeparser"
+	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -14,85 +19,85 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 		bookings []Booking
 		expected MaximizeProfit
 	}{
-		"test combo ( B + C + D )": {
+		"test combo ( B + D )": {
 			bookings: []Booking{
 				{
 					RequestID:   "A",
 					CheckIn:     parse("2023-01-05"),
 					Nights:      5,
-					SellingRate: 200,
+					SellingRate: money.FromUnits(200),
 					Margin:      20,
 				},
 				{
 					RequestID:   "B",
 					CheckIn:     parse("2023-01-04"),
 					Nights:      4,
-					SellingRate: 156,
+					SellingRate: money.FromUnits(156),
 					Margin:      5,
 				},
 				{
 					RequestID:   "C",
 					CheckIn:     parse("2023-01-09"),
 					Nights:      4,
-					SellingRate: 150,
+					SellingRate: money.FromUnits(150),
 					Margin:      6,
 				},
 				{
 					RequestID:   "D",
 					CheckIn:     parse("2023-01-09"),
 					Nights:      1,
-					SellingRate: 1600,
+					SellingRate: money.FromUnits(1600),
 					Margin:      30,
 				},
 			},
 			expected: MaximizeProfit{
-				RequestIDS:  []string{"B", "D", "C"},
-				TotalProfit: 496,
+				RequestIDS:  []string{"B", "D"},
+				TotalProfit: money.MustParse("487.8"),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 161.4,
-					MinNight: 1.95,
-					MaxNight: 480,
+					AvgNight: money.MustParse("240.98"),
+					MinNight: money.MustParse("1.95"),
+					MaxNight: money.FromUnits(480),
 				},
 			},
 		},
-		"unsorted test where booking no overlap each other": {
+		"unsorted test where B and D overlap": {
 			bookings: []Booking{
 				{
 					RequestID:   "A",
 					CheckIn:     parse("2023-02-05"),
 					Nights:      5,
-					SellingRate: 55,
+					SellingRate: money.FromUnits(55),
 					Margin:      6,
 				},
 				{
 					RequestID:   "B",
 					CheckIn:     parse("2023-01-17"),
 					Nights:      7,
-					SellingRate: 231,
+					SellingRate: money.FromUnits(231),
 					Margin:      12,
 				},
 				{
 					RequestID:   "C",
 					CheckIn:     parse("2023-01-04"),
 					Nights:      4,
-					SellingRate: 150,
+					SellingRate: money.FromUnits(150),
 					Margin:      6,
 				},
 				{
 					RequestID:   "D",
 					CheckIn:     parse("2023-01-22"),
 					Nights:      1,
-					SellingRate: 1600,
+					SellingRate: money.FromUnits(1600),
 					Margin:      30,
 				},
 			},
 			expected: MaximizeProfit{
-				RequestIDS:  []string{"C", "A", "D", "B"},
-				TotalProfit: 519,
+				RequestIDS:  []string{"C", "D", "A"},
+				TotalProfit: money.MustParse("492.3"),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 121.72,
-					MinNight: 0.66,
-					MaxNight: 480,
+					AvgNight: money.MustParse("160.97"),
+					MinNight: money.MustParse("0.66"),
+					MaxNight: money.FromUnits(480),
 				},
 			},
 		},
@@ -102,17 +107,17 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 					RequestID:   "A",
 					CheckIn:     parse("2023-01-05"),
 					Nights:      5,
-					SellingRate: 200,
+					SellingRate: money.FromUnits(200),
 					Margin:      20,
 				},
 			},
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"A"},
-				TotalProfit: 40,
+				TotalProfit: money.FromUnits(40),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 8,
-					MinNight: 8,
-					MaxNight: 8,
+					AvgNight: money.FromUnits(8),
+					MinNight: money.FromUnits(8),
+					MaxNight: money.FromUnits(8),
 				},
 			},
 		},
@@ -122,31 +127,31 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 					RequestID:   "A",
 					CheckIn:     parse("2018-01-01"),
 					Nights:      10,
-					SellingRate: 1000,
+					SellingRate: money.FromUnits(1000),
 					Margin:      10,
 				},
 				{
 					RequestID:   "B",
 					CheckIn:     parse("2018-01-06"),
 					Nights:      10,
-					SellingRate: 700,
+					SellingRate: money.FromUnits(700),
 					Margin:      10,
 				},
 				{
 					RequestID:   "C",
 					CheckIn:     parse("2018-01-12"),
 					Nights:      10,
-					SellingRate: 400,
+					SellingRate: money.FromUnits(400),
 					Margin:      10,
 				},
 			},
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"A", "C"},
-				TotalProfit: 140,
+				TotalProfit: money.FromUnits(140),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 7,
-					MinNight: 4,
-					MaxNight: 10,
+					AvgNight: money.FromUnits(7),
+					MinNight: money.FromUnits(4),
+					MaxNight: money.FromUnits(10),
 				},
 			},
 		},
@@ -156,38 +161,38 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 					RequestID:   "bookata_XY123",
 					CheckIn:     parse("2020-01-01"),
 					Nights:      5,
-					SellingRate: 200,
+					SellingRate: money.FromUnits(200),
 					Margin:      20,
 				},
 				{
 					RequestID:   "kayete_PP234",
 					CheckIn:     parse("2020-01-04"),
 					Nights:      4,
-					SellingRate: 156,
+					SellingRate: money.FromUnits(156),
 					Margin:      5,
 				},
 				{
 					RequestID:   "atropote_AA930",
 					CheckIn:     parse("2020-01-04"),
 					Nights:      4,
-					SellingRate: 150,
+					SellingRate: money.FromUnits(150),
 					Margin:      6,
 				},
 				{
 					RequestID:   "acme_AAAAA",
 					CheckIn:     parse("2020-01-10"),
 					Nights:      4,
-					SellingRate: 160,
+					SellingRate: money.FromUnits(160),
 					Margin:      30,
 				},
 			},
 			expected: MaximizeProfit{
 				RequestIDS:  []string{"bookata_XY123", "acme_AAAAA"},
-				TotalProfit: 88,
+				TotalProfit: money.FromUnits(88),
 				ProfitPerNight: ProfitPerNight{
-					AvgNight: 10,
-					MinNight: 8,
-					MaxNight: 12,
+					AvgNight: money.FromUnits(10),
+					MinNight: money.FromUnits(8),
+					MaxNight: money.FromUnits(12),
 				},
 			},
 		},
@@ -197,13 +202,16 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 		tt := tt
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
-			got := maximizeService.MaximTotalProfits(tt.bookings)
+			got, err := newMaximizeService(t).MaximTotalProfits(tt.bookings)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if !reflect.DeepEqual(got.RequestIDS, tt.expected.RequestIDS) {
 				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected.RequestIDS)
 			}
 
 			if got.TotalProfit != tt.expected.TotalProfit {
-				t.Errorf("got: %d, expected: %d", got.TotalProfit, tt.expected.TotalProfit)
+				t.Errorf("got: %v, expected: %v", got.TotalProfit, tt.expected.TotalProfit)
 			}
 
 			if got.ProfitPerNight != tt.expected.ProfitPerNight {
@@ -213,6 +221,651 @@ func TestMaximizeService_MaximTotalProfits(t *testing.T) {
 	}
 }
 
+func TestMaximizeService_MaximTotalProfitsWithRooms(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2018-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2018-01-06"),
+			Nights:      10,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      10,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2018-01-08"),
+			Nights:      2,
+			SellingRate: money.FromUnits(100),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		rooms    int
+		expected MaximizeProfit
+	}{
+		"single room ( A + C )": {
+			rooms: 1,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "C"},
+				TotalProfit: money.FromUnits(140),
+				Rooms: []RoomAssignment{
+					{Room: 1, RequestIDS: []string{"A", "C"}},
+				},
+			},
+		},
+		"two rooms ( A + B + C )": {
+			rooms: 2,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "B", "C"},
+				TotalProfit: money.FromUnits(210),
+				Rooms: []RoomAssignment{
+					{Room: 1, RequestIDS: []string{"A", "C"}},
+					{Room: 2, RequestIDS: []string{"B"}},
+				},
+			},
+		},
+		"more rooms than bookings": {
+			// the rooms past one per booking are not listed.
+			rooms: 5,
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "B", "D", "C"},
+				TotalProfit: money.FromUnits(220),
+				Rooms: []RoomAssignment{
+					{Room: 1, RequestIDS: []string{"A", "C"}},
+					{Room: 2, RequestIDS: []string{"B"}},
+					{Room: 3, RequestIDS: []string{"D"}},
+					{Room: 4, RequestIDS: []string{}},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithRooms(tt.rooms))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expected.RequestIDS) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected.RequestIDS)
+			}
+
+			if got.TotalProfit != tt.expected.TotalProfit {
+				t.Errorf("got: %v, expected: %v", got.TotalProfit, tt.expected.TotalProfit)
+			}
+
+			if !reflect.DeepEqual(got.Rooms, tt.expected.Rooms) {
+				t.Errorf("got: %v, expected: %v", got.Rooms, tt.expected.Rooms)
+			}
+		})
+	}
+
+	for _, rooms := range []int{0, maxRooms + 1} {
+		_, err := newMaximizeService(t).MaximTotalProfits(bookings, WithRooms(rooms))
+		if err != errRooms {
+			t.Errorf("got: %v, expected: %v with %d rooms", err, errRooms, rooms)
+		}
+	}
+}
+
+func TestMaximizeService_TopTotalProfits(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2018-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2018-01-06"),
+			Nights:      10,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      10,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		k        int
+		opts     []MaximizeOption
+		expected []MaximizeProfit
+	}{
+		"best three": {
+			k: 3,
+			expected: []MaximizeProfit{
+				{RequestIDS: []string{"A", "C"}, TotalProfit: money.FromUnits(140)},
+				{RequestIDS: []string{"A"}, TotalProfit: money.FromUnits(100)},
+				{RequestIDS: []string{"B"}, TotalProfit: money.FromUnits(70)},
+			},
+		},
+		"more than available": {
+			k: 10,
+			expected: []MaximizeProfit{
+				{RequestIDS: []string{"A", "C"}, TotalProfit: money.FromUnits(140)},
+				{RequestIDS: []string{"A"}, TotalProfit: money.FromUnits(100)},
+				{RequestIDS: []string{"B"}, TotalProfit: money.FromUnits(70)},
+				{RequestIDS: []string{"C"}, TotalProfit: money.FromUnits(40)},
+			},
+		},
+		"two rooms": {
+			k:    2,
+			opts: []MaximizeOption{WithRooms(2)},
+			expected: []MaximizeProfit{
+				{RequestIDS: []string{"A", "B", "C"}, TotalProfit: money.FromUnits(210)},
+				{RequestIDS: []string{"A", "B"}, TotalProfit: money.FromUnits(170)},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).TopTotalProfits(bookings, tt.k, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.expected) {
+				t.Fatalf("got %d combinations, expected %d", len(got), len(tt.expected))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i].RequestIDS, tt.expected[i].RequestIDS) {
+					t.Errorf("got: %v, expected: %v", got[i].RequestIDS, tt.expected[i].RequestIDS)
+				}
+
+				if got[i].TotalProfit != tt.expected[i].TotalProfit {
+					t.Errorf("got: %v, expected: %v", got[i].TotalProfit, tt.expected[i].TotalProfit)
+				}
+			}
+		})
+	}
+
+	_, err := newMaximizeService(t).TopTotalProfits(bookings, 0)
+	if err != errTop {
+		t.Errorf("got: %v, expected: %v", err, errTop)
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsWithConstraints(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2018-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2018-01-06"),
+			Nights:      10,
+			SellingRate: money.FromUnits(700),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      10,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2018-01-12"),
+			Nights:      0,
+			SellingRate: money.FromUnits(400),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		opts     []MaximizeOption
+		expected MaximizeProfit
+		err      error
+	}{
+		"pinned B": {
+			opts: []MaximizeOption{WithPinned("B")},
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"B"},
+				TotalProfit: money.FromUnits(70),
+			},
+		},
+		"excluded A": {
+			opts: []MaximizeOption{WithExcluded("A")},
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"B"},
+				TotalProfit: money.FromUnits(70),
+			},
+		},
+		"pinned C and excluded A": {
+			opts: []MaximizeOption{WithPinned("C"), WithExcluded("A")},
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"C"},
+				TotalProfit: money.FromUnits(40),
+			},
+		},
+		"pinned overlapping bookings in two rooms": {
+			opts: []MaximizeOption{WithRooms(2), WithPinned("A", "B")},
+			expected: MaximizeProfit{
+				RequestIDS:  []string{"A", "B", "C"},
+				TotalProfit: money.FromUnits(210),
+				Rooms: []RoomAssignment{
+					{Room: 1, RequestIDS: []string{"A", "C"}},
+					{Room: 2, RequestIDS: []string{"B"}},
+				},
+			},
+		},
+		"pinned overlapping bookings": {
+			opts: []MaximizeOption{WithPinned("A", "B")},
+			err:  errPinnedOverlap,
+		},
+		"pinned and excluded": {
+			opts: []MaximizeOption{WithPinned("A"), WithExcluded("A")},
+			err:  errPinnedExcluded,
+		},
+		"unknown request id": {
+			opts: []MaximizeOption{WithExcluded("Z")},
+			err:  errUnknownRequestID,
+		},
+		"pinned invalid booking": {
+			opts: []MaximizeOption{WithPinned("D")},
+			err:  errPinnedInvalid,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(bookings, tt.opts...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got: %v, expected: %v", err, tt.err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expected.RequestIDS) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected.RequestIDS)
+			}
+
+			if got.TotalProfit != tt.expected.TotalProfit {
+				t.Errorf("got: %v, expected: %v", got.TotalProfit, tt.expected.TotalProfit)
+			}
+
+			if !reflect.DeepEqual(got.Rooms, tt.expected.Rooms) {
+				t.Errorf("got: %v, expected: %v", got.Rooms, tt.expected.Rooms)
+			}
+		})
+	}
+}
+
+// TestMaximizeService_MaximTotalProfitsWithConstraintsOracle cross-checks pinned and excluded bookings against an
+// exhaustive search on random inputs.
+func TestMaximizeService_MaximTotalProfitsWithConstraintsOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(87))
+	for run := 0; run < 300; run++ {
+		var (
+			bookings         = randomBookings(rnd, 1+rnd.Intn(8))
+			rooms            = 1 + rnd.Intn(2)
+			pinned, excluded []string
+		)
+		for _, b := range bookings {
+			switch rnd.Intn(6) {
+			case 0:
+				if b.valid() {
+					pinned = append(pinned, b.RequestID)
+				}
+			case 1:
+				excluded = append(excluded, b.RequestID)
+			}
+		}
+
+		got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithRooms(rooms), WithPinned(pinned...), WithExcluded(excluded...))
+
+		expected, feasible := bruteForceConstrainedMaxProfit(bookings, rooms, pinned, excluded)
+		if !feasible {
+			if !errors.Is(err, errPinnedOverlap) {
+				t.Fatalf("run %d: got: %v, expected: %v", run, err, errPinnedOverlap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.TotalProfit != expected {
+			t.Fatalf("run %d: got: %v, expected: %v", run, got.TotalProfit, expected)
+		}
+
+		ids := make(map[string]struct{}, len(got.RequestIDS))
+		for _, id := range got.RequestIDS {
+			ids[id] = struct{}{}
+		}
+		for _, id := range pinned {
+			if _, ok := ids[id]; !ok {
+				t.Fatalf("run %d: pinned %s missing from %v", run, id, got.RequestIDS)
+			}
+		}
+		for _, id := range excluded {
+			if _, ok := ids[id]; ok {
+				t.Fatalf("run %d: excluded %s found in %v", run, id, got.RequestIDS)
+			}
+		}
+	}
+}
+
+// TestMaximizeService_TopTotalProfitsOracle checks the k best combinations against every feasible one.
+func TestMaximizeService_TopTotalProfitsOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(87))
+	for run := 0; run < 200; run++ {
+		var (
+			bookings = randomBookings(rnd, 1+rnd.Intn(8))
+			rooms    = 1 + rnd.Intn(2)
+			k        = 1 + rnd.Intn(6)
+		)
+
+		got, err := newMaximizeService(t).TopTotalProfits(bookings, k, WithRooms(rooms))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := bruteForceProfits(bookings, rooms)
+		if len(expected) > k {
+			expected = expected[:k]
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("run %d: got %d combinations, expected %d", run, len(got), len(expected))
+		}
+
+		seen := make(map[string]struct{}, len(got))
+		for i, combination := range got {
+			if combination.TotalProfit != expected[i] {
+				t.Fatalf("run %d: combination %d got: %v, expected: %v", run, i, combination.TotalProfit, expected[i])
+			}
+
+			if !fits(selectedBookings(bookings, combination.RequestIDS), rooms) {
+				t.Fatalf("run %d: selection %v does not fit in %d rooms", run, combination.RequestIDS, rooms)
+			}
+
+			key := fmt.Sprint(combination.RequestIDS)
+			if _, ok := seen[key]; ok {
+				t.Fatalf("run %d: selection %v returned twice", run, combination.RequestIDS)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsWithBufferNights(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      2,
+			SellingRate: money.FromUnits(100),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2023-01-03"),
+			Nights:      2,
+			SellingRate: money.FromUnits(200),
+			Margin:      10,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2023-01-06"),
+			Nights:      2,
+			SellingRate: money.FromUnits(300),
+			Margin:      10,
+		},
+	}
+
+	tests := map[string]struct {
+		service  *MaximizeService
+		opts     []MaximizeOption
+		expected []string
+	}{
+		"check out day blocked by default": {
+			service:  newMaximizeService(t),
+			expected: []string{"B", "C"},
+		},
+		"same day turnover": {
+			service:  newMaximizeService(t),
+			opts:     []MaximizeOption{WithSameDayTurnover()},
+			expected: []string{"A", "B", "C"},
+		},
+		"two nights buffer": {
+			service:  newMaximizeService(t),
+			opts:     []MaximizeOption{WithBufferNights(2)},
+			expected: []string{"A", "C"},
+		},
+		"same day turnover as service default": {
+			service:  newMaximizeService(t, WithSameDayTurnover()),
+			expected: []string{"A", "B", "C"},
+		},
+		"request overriding the service default": {
+			service:  newMaximizeService(t, WithSameDayTurnover()),
+			opts:     []MaximizeOption{WithBufferNights(1)},
+			expected: []string{"B", "C"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.service.MaximTotalProfits(bookings, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected)
+			}
+		})
+	}
+
+	_, err := NewMaximizeService(newStatsService(t), WithBufferNights(-1))
+	if err != errBufferNights {
+		t.Errorf("got: %v, expected: %v", err, errBufferNights)
+	}
+}
+
+// TestMaximizeService_MaximTotalProfitsOracle cross-checks the solver against an exhaustive search on random inputs.
+func TestMaximizeService_MaximTotalProfitsOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(87))
+	for run := 0; run < 500; run++ {
+		var (
+			bookings     = randomBookings(rnd, 1+rnd.Intn(10))
+			rooms        = 1 + rnd.Intn(3)
+			bufferNights = rnd.Intn(3)
+		)
+
+		got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithRooms(rooms), WithBufferNights(bufferNights))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := bruteForceMaxProfit(bookings, rooms, bufferNights)
+		if got.TotalProfit != expected {
+			t.Fatalf("run %d: got: %v, expected: %v, rooms: %d, bookings: %+v", run, got.TotalProfit, expected, rooms, bookings)
+		}
+
+		selected := selectedBookings(bookings, got.RequestIDS)
+		if !fitsWithBuffer(selected, rooms, bufferNights) {
+			t.Fatalf("run %d: selection %v does not fit in %d rooms", run, got.RequestIDS, rooms)
+		}
+		if sum := totalProfit(selected); sum != got.TotalProfit {
+			t.Fatalf("run %d: selection %v sums %v, reported %v", run, got.RequestIDS, sum, got.TotalProfit)
+		}
+		for _, room := range got.Rooms {
+			if !fitsWithBuffer(selectedBookings(bookings, room.RequestIDS), 1, bufferNights) {
+				t.Fatalf("run %d: room %d has overlapping bookings %v", run, room.Room, room.RequestIDS)
+			}
+		}
+	}
+}
+
+func randomBookings(rnd *rand.Rand, n int) []Booking {
+	start := parse("2023-01-01")
+	bookings := make([]Booking, 0, n)
+	for i := 0; i < n; i++ {
+		bookings = append(bookings, Booking{
+			RequestID:   fmt.Sprintf("R%d", i),
+			CheckIn:     start.AddDate(0, 0, rnd.Intn(30)),
+			Nights:      int32(1 + rnd.Intn(7)),
+			SellingRate: money.FromMinor(int64(rnd.Intn(100000))),
+			Margin:      int32(rnd.Intn(40)),
+		})
+	}
+	return bookings
+}
+
+func bruteForceMaxProfit(bookings []Booking, rooms, bufferNights int) money.Amount {
+	var best money.Amount
+	for mask := 0; mask < 1<<len(bookings); mask++ {
+		var subset []Booking
+		for i, b := range bookings {
+			if mask&(1<<i) != 0 && b.valid() {
+				subset = append(subset, b)
+			}
+		}
+		if p := totalProfit(subset); p > best && fitsWithBuffer(subset, rooms, bufferNights) {
+			best = p
+		}
+	}
+	return best
+}
+
+func bruteForceConstrainedMaxProfit(bookings []Booking, rooms int, pinned, excluded []string) (money.Amount, bool) {
+	var (
+		best     money.Amount
+		feasible bool
+		required = make(map[string]struct{}, len(pinned))
+		banned   = make(map[string]struct{}, len(excluded))
+	)
+	for _, id := range pinned {
+		required[id] = struct{}{}
+	}
+	for _, id := range excluded {
+		banned[id] = struct{}{}
+	}
+
+Subsets:
+	for mask := 0; mask < 1<<len(bookings); mask++ {
+		var subset []Booking
+		for i, b := range bookings {
+			_, isRequired := required[b.RequestID]
+			_, isBanned := banned[b.RequestID]
+			in := mask&(1<<i) != 0
+			if in && (isBanned || !b.valid()) || !in && isRequired {
+				continue Subsets
+			}
+			if in {
+				subset = append(subset, b)
+			}
+		}
+		if p := totalProfit(subset); (!feasible || p > best) && fits(subset, rooms) {
+			best = p
+			feasible = true
+		}
+	}
+	return best, feasible
+}
+
+// bruteForceProfits returns the total profit of every non-empty feasible combination, highest first.
+func bruteForceProfits(bookings []Booking, rooms int) []money.Amount {
+	var profits []money.Amount
+	for mask := 1; mask < 1<<len(bookings); mask++ {
+		var subset []Booking
+		for i, b := range bookings {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			if !b.valid() {
+				subset = nil
+				break
+			}
+			subset = append(subset, b)
+		}
+		if len(subset) > 0 && fits(subset, rooms) {
+			profits = append(profits, totalProfit(subset))
+		}
+	}
+	sort.Slice(profits, func(i, j int) bool {
+		return profits[i] > profits[j]
+	})
+	return profits
+}
+
+func selectedBookings(bookings []Booking, ids []string) []Booking {
+	byID := make(map[string]Booking, len(bookings))
+	for _, b := range bookings {
+		byID[b.RequestID] = b
+	}
+	selected := make([]Booking, 0, len(ids))
+	for _, id := range ids {
+		selected = append(selected, byID[id])
+	}
+	return selected
+}
+
+// fits tells whether no more than rooms bookings overlap at once, checking the start of every stay.
+func fits(bookings []Booking, rooms int) bool {
+	return fitsWithBuffer(bookings, rooms, defaultBufferNights)
+}
+
+func fitsWithBuffer(bookings []Booking, rooms, bufferNights int) bool {
+	for _, b := range bookings {
+		var (
+			day      = newStay(b, bufferNights)
+			overlaps int
+		)
+		for _, o := range bookings {
+			if s := newStay(o, bufferNights); s.start <= day.start && day.start < s.end {
+				overlaps++
+			}
+		}
+		if overlaps > rooms {
+			return false
+		}
+	}
+	return true
+}
+
+func totalProfit(bookings []Booking) money.Amount {
+	var total money.Amount
+	for _, b := range bookings {
+		total += profit(b.SellingRate, b.Margin)
+	}
+	return total
+}
+
 var MaxGlobal MaximizeProfit
 
 func BenchmarkMaximTotalProfits(b *testing.B) {
@@ -221,37 +874,38 @@ func BenchmarkMaximTotalProfits(b *testing.B) {
 			RequestID:   "bookata_XY123",
 			CheckIn:     parse("2020-01-01"),
 			Nights:      5,
-			SellingRate: 200,
+			SellingRate: money.FromUnits(200),
 			Margin:      20,
 		},
 		{
 			RequestID:   "kayete_PP234",
 			CheckIn:     parse("2020-01-04"),
 			Nights:      4,
-			SellingRate: 156,
+			SellingRate: money.FromUnits(156),
 			Margin:      5,
 		},
 		{
 			RequestID:   "atropote_AA930",
 			CheckIn:     parse("2020-01-04"),
 			Nights:      4,
-			SellingRate: 150,
+			SellingRate: money.FromUnits(150),
 			Margin:      6,
 		},
 		{
 			RequestID:   "acme_AAAAA",
 			CheckIn:     parse("2020-01-10"),
 			Nights:      4,
-			SellingRate: 160,
+			SellingRate: money.FromUnits(160),
 			Margin:      30,
 		},
 	}
 
 	var max MaximizeProfit
+	service := newMaximizeService(b)
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		max = maximizeService.MaximTotalProfits(test)
+		max, _ = service.MaximTotalProfits(test)
 	}
 	MaxGlobal = max
 }
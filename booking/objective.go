@@ -0,0 +1,73 @@
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+)
+
+var errObjective = errors.New("objective should be one of profit, nights, revenue or avg_profit_per_night")
+
+// Objective is what maximize looks for in a combination. Ties are broken by the highest total profit, then the
+// fewest bookings, then the earliest check-ins.
+type Objective string
+
+const (
+	ObjectiveProfit            Objective = "profit"
+	ObjectiveNights            Objective = "nights"
+	ObjectiveRevenue           Objective = "revenue"
+	ObjectiveAvgProfitPerNight Objective = "avg_profit_per_night"
+)
+
+// avgScale turns profits per night into integer millionths of a unit, so combinations are ranked by average closer
+// than to the cent.
+const avgScale = 1_000_000
+
+func (o Objective) valid() bool {
+	switch o {
+	case ObjectiveProfit, ObjectiveNights, ObjectiveRevenue, ObjectiveAvgProfitPerNight:
+		return true
+	}
+	return false
+}
+
+// additive tells whether the objective of a combination is the sum of the values of its bookings.
+func (o Objective) additive() bool {
+	return o != ObjectiveAvgProfitPerNight
+}
+
+// bookingValue returns what b brings to the objective. For the average, it's its own profit per night in avgScale.
+func (o Objective) bookingValue(b Booking) int64 {
+	switch o {
+	case ObjectiveNights:
+		return int64(b.Nights)
+	case ObjectiveRevenue:
+		return b.SellingRate.Minor()
+	case ObjectiveAvgProfitPerNight:
+		return b.SellingRate.MulDiv(int64(b.Margin)*avgScale, 100*money.Scale*int64(b.Nights), money.HalfEven).Minor()
+	default:
+		return profit(b.SellingRate, b.Margin).Minor()
+	}
+}
+
+// value returns the objective reached by the bookings of a combination, as reported next to it: an amount in the
+// reporting currency, but for the nights, their count in whole units. The sums fit, as the problem checked its range.
+func (o Objective) value(combination MaximizeProfit, bookings []Booking) money.Amount {
+	switch o {
+	case ObjectiveNights:
+		var total int64
+		for _, b := range bookings {
+			total += o.bookingValue(b)
+		}
+		return money.FromUnits(total)
+	case ObjectiveRevenue:
+		var total money.Amount
+		for _, b := range bookings {
+			total += b.SellingRate
+		}
+		return total
+	case ObjectiveAvgProfitPerNight:
+		return combination.AvgNight
+	default:
+		return combination.TotalProfit
+	}
+}
@@ -0,0 +1,235 @@
+//go:build unit
+
+package booking
+
+import (
+	"github.com/xsolrac87/booking/money"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMaximizeService_MaximTotalProfitsWithObjective(t *testing.T) {
+	bookings := []Booking{
+		{
+			RequestID:   "A",
+			CheckIn:     parse("2023-01-01"),
+			Nights:      10,
+			SellingRate: money.FromUnits(1000),
+			Margin:      10,
+		},
+		{
+			RequestID:   "B",
+			CheckIn:     parse("2023-01-03"),
+			Nights:      2,
+			SellingRate: money.FromUnits(600),
+			Margin:      20,
+		},
+		{
+			RequestID:   "C",
+			CheckIn:     parse("2023-01-06"),
+			Nights:      3,
+			SellingRate: money.FromUnits(300),
+			Margin:      10,
+		},
+		{
+			RequestID:   "D",
+			CheckIn:     parse("2023-01-12"),
+			Nights:      1,
+			SellingRate: money.FromUnits(100),
+			Margin:      50,
+		},
+	}
+
+	tests := map[string]struct {
+		objective     Objective
+		expectedIDS   []string
+		expectedValue money.Amount
+	}{
+		"profit": {
+			objective:     ObjectiveProfit,
+			expectedIDS:   []string{"B", "C", "D"},
+			expectedValue: money.FromUnits(200),
+		},
+		"nights": {
+			objective:     ObjectiveNights,
+			expectedIDS:   []string{"A", "D"},
+			expectedValue: money.FromUnits(11),
+		},
+		"revenue": {
+			objective:     ObjectiveRevenue,
+			expectedIDS:   []string{"A", "D"},
+			expectedValue: money.FromUnits(1100),
+		},
+		"average profit per night": {
+			objective:     ObjectiveAvgProfitPerNight,
+			expectedIDS:   []string{"B"},
+			expectedValue: money.FromUnits(60),
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithObjective(tt.objective))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expectedIDS) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expectedIDS)
+			}
+
+			if got.Objective != tt.objective {
+				t.Errorf("got: %s, expected: %s", got.Objective, tt.objective)
+			}
+
+			if got.ObjectiveValue != tt.expectedValue {
+				t.Errorf("got: %s, expected: %s", got.ObjectiveValue, tt.expectedValue)
+			}
+		})
+	}
+
+	_, err := newMaximizeService(t).MaximTotalProfits(bookings, WithObjective("occupancy"))
+	if err != errObjective {
+		t.Errorf("got: %v, expected: %v", err, errObjective)
+	}
+}
+
+func TestMaximizeService_MaximTotalProfitsTieBreakers(t *testing.T) {
+	tests := map[string]struct {
+		bookings  []Booking
+		objective Objective
+		expected  []string
+	}{
+		"highest profit on same nights": {
+			bookings: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 4, SellingRate: money.FromUnits(100), Margin: 10},
+				{RequestID: "B", CheckIn: parse("2023-01-02"), Nights: 4, SellingRate: money.FromUnits(200), Margin: 10},
+			},
+			objective: ObjectiveNights,
+			expected:  []string{"B"},
+		},
+		"fewest bookings on same profit": {
+			bookings: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10},
+				{RequestID: "B", CheckIn: parse("2023-01-01"), Nights: 6, SellingRate: money.FromUnits(200), Margin: 10},
+				{RequestID: "C", CheckIn: parse("2023-01-05"), Nights: 1, SellingRate: money.FromUnits(100), Margin: 10},
+			},
+			objective: ObjectiveProfit,
+			expected:  []string{"B"},
+		},
+		"earliest check in on same profit and bookings": {
+			bookings: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-03"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10},
+				{RequestID: "B", CheckIn: parse("2023-01-02"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10},
+			},
+			objective: ObjectiveProfit,
+			expected:  []string{"B"},
+		},
+		"highest profit on same average": {
+			bookings: []Booking{
+				{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: money.FromUnits(100), Margin: 10},
+				{RequestID: "B", CheckIn: parse("2023-01-01"), Nights: 4, SellingRate: money.FromUnits(200), Margin: 10},
+				{RequestID: "C", CheckIn: parse("2023-01-10"), Nights: 1, SellingRate: money.FromUnits(50), Margin: 10},
+			},
+			objective: ObjectiveAvgProfitPerNight,
+			expected:  []string{"B", "C"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newMaximizeService(t).MaximTotalProfits(tt.bookings, WithObjective(tt.objective))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.RequestIDS, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got.RequestIDS, tt.expected)
+			}
+		})
+	}
+}
+
+// TestMaximizeService_MaximTotalProfitsWithObjectiveOracle cross-checks every objective, and the total profit
+// breaking its ties, against an exhaustive search on random inputs.
+func TestMaximizeService_MaximTotalProfitsWithObjectiveOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(87))
+	objectives := []Objective{ObjectiveProfit, ObjectiveNights, ObjectiveRevenue, ObjectiveAvgProfitPerNight}
+	for run := 0; run < 400; run++ {
+		var (
+			bookings  = randomBookings(rnd, 1+rnd.Intn(9))
+			rooms     = 1 + rnd.Intn(2)
+			objective = objectives[rnd.Intn(len(objectives))]
+		)
+
+		got, err := newMaximizeService(t).MaximTotalProfits(bookings, WithRooms(rooms), WithObjective(objective))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		selected := selectedBookings(bookings, got.RequestIDS)
+		if !fits(selected, rooms) {
+			t.Fatalf("run %d: selection %v does not fit in %d rooms", run, got.RequestIDS, rooms)
+		}
+
+		best, bestProfit := bruteForceObjective(bookings, rooms, objective)
+		value, count := objectiveSum(selected, objective)
+		if value*best.count != best.value*count {
+			t.Fatalf("run %d: %s got: %d/%d, expected: %d/%d", run, objective, value, count, best.value, best.count)
+		}
+		if got.TotalProfit != bestProfit {
+			t.Fatalf("run %d: %s got profit: %v, expected: %v", run, objective, got.TotalProfit, bestProfit)
+		}
+	}
+}
+
+type objectiveRatio struct {
+	value, count int64
+}
+
+// bruteForceObjective returns the best objective among every feasible combination, as a ratio to compare averages
+// exactly, and the highest total profit reaching it.
+func bruteForceObjective(bookings []Booking, rooms int, objective Objective) (objectiveRatio, money.Amount) {
+	var (
+		best       = objectiveRatio{count: 1}
+		bestProfit money.Amount
+	)
+	for mask := 1; mask < 1<<len(bookings); mask++ {
+		var subset []Booking
+		for i, b := range bookings {
+			if mask&(1<<i) != 0 && b.valid() {
+				subset = append(subset, b)
+			}
+		}
+		if !fits(subset, rooms) {
+			continue
+		}
+
+		value, count := objectiveSum(subset, objective)
+		p := totalProfit(subset)
+		switch {
+		case value*best.count > best.value*count:
+			best, bestProfit = objectiveRatio{value: value, count: count}, p
+		case value*best.count == best.value*count && p > bestProfit:
+			bestProfit = p
+		}
+	}
+	return best, bestProfit
+}
+
+// objectiveSum returns the objective of the bookings as a ratio, whose count is 1 unless the objective is an average.
+func objectiveSum(bookings []Booking, objective Objective) (int64, int64) {
+	var value int64
+	for _, b := range bookings {
+		value += objective.bookingValue(b)
+	}
+	if objective.additive() || len(bookings) == 0 {
+		return value, 1
+	}
+	return value, int64(len(bookings))
+}
@@ -0,0 +1,164 @@
+package booking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// problemContentType is the media type of the problem documents (RFC 7807).
+	problemContentType = "application/problem+json"
+	// problemTypePrefix makes the problem type URI of a code.
+	problemTypePrefix = "urn:booking:problem:"
+
+	codeInvalidJSON = "invalid_json"
+	codeInternal    = "internal_error"
+)
+
+// RequestIDHeader carries the ID of a request, the correlation ID of its logs and problem documents.
+const RequestIDHeader = "X-Request-ID"
+
+// Problem is the JSON problem document (RFC 7807) every error is answered with. Code is a stable, machine-readable
+// name of the error, and CorrelationID the ID of the request in the server logs.
+type Problem struct {
+	Type          string  `json:"type"`
+	Title         string  `json:"title"`
+	Status        int     `json:"status"`
+	Detail        string  `json:"detail"`
+	Instance      string  `json:"instance,omitempty"`
+	Code          string  `json:"code"`
+	CorrelationID string  `json:"correlation_id"`
+	Issues        []Issue `json:"issues,omitempty"`
+}
+
+// problems maps every error the handler answers to its code and HTTP status. Codes are part of the API: never change
+// one, add a new error instead.
+var problems = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{err: errInvalidHttpMethod, code: "method_not_allowed", status: http.StatusMethodNotAllowed},
+	{err: errNotFound, code: "not_found", status: http.StatusNotFound},
+
+	{err: errRequestBody, code: "unreadable_body", status: http.StatusBadRequest},
+	{err: errBodyTooLarge, code: "body_too_large", status: http.StatusRequestEntityTooLarge},
+	{err: errInvalidRequestBody, code: "no_bookings", status: http.StatusBadRequest},
+	{err: errInvalidQueryParam, code: "invalid_query_param", status: http.StatusBadRequest},
+	{err: errValidationMode, code: "invalid_validation", status: http.StatusBadRequest},
+	{err: errInvalidBookings, code: "invalid_bookings", status: http.StatusBadRequest},
+	{err: errBookingType, code: "booking_not_object", status: http.StatusBadRequest},
+	{err: errWrongType, code: "wrong_type", status: http.StatusBadRequest},
+	{err: errRequestIDMissing, code: "request_id_missing", status: http.StatusBadRequest},
+	{err: errCheckInMissing, code: "check_in_missing", status: http.StatusBadRequest},
+	{err: errCheckInInvalid, code: "check_in_invalid", status: http.StatusBadRequest},
+	{err: errNightsMissing, code: "nights_missing", status: http.StatusBadRequest},
+	{err: errSellingRateMissing, code: "selling_rate_missing", status: http.StatusBadRequest},
+	{err: errSellingRateInvalid, code: "selling_rate_invalid", status: http.StatusBadRequest},
+	{err: errMarginMissing, code: "margin_missing", status: http.StatusBadRequest},
+	{err: fx.ErrCurrency, code: "invalid_currency", status: http.StatusBadRequest},
+	{err: errRooms, code: "invalid_rooms", status: http.StatusBadRequest},
+	{err: errBufferNights, code: "invalid_buffer_nights", status: http.StatusBadRequest},
+	{err: errObjective, code: "invalid_objective", status: http.StatusBadRequest},
+	{err: errTop, code: "invalid_top", status: http.StatusBadRequest},
+	{err: errExplainTop, code: "explain_with_top", status: http.StatusBadRequest},
+	{err: errFrontierRooms, code: "frontier_rooms", status: http.StatusBadRequest},
+	{err: errHorizon, code: "invalid_horizon", status: http.StatusBadRequest},
+	{err: errHorizonMode, code: "invalid_straddling", status: http.StatusBadRequest},
+	{err: errGroupBy, code: "invalid_group_by", status: http.StatusBadRequest},
+	{err: errBins, code: "invalid_bins", status: http.StatusBadRequest},
+	{err: errBinWidth, code: "invalid_bin_width", status: http.StatusBadRequest},
+	{err: errBinEdges, code: "invalid_bin_edges", status: http.StatusBadRequest},
+	{err: errQuantiles, code: "invalid_quantiles", status: http.StatusBadRequest},
+	{err: errTooManyBuckets, code: "too_many_buckets", status: http.StatusBadRequest},
+	{err: errCalendarSpan, code: "calendar_span_too_long", status: http.StatusBadRequest},
+	{err: errDuplicatePolicy, code: "invalid_duplicates", status: http.StatusBadRequest},
+	{err: errCSV, code: "invalid_csv", status: http.StatusBadRequest},
+	{err: errCSVHeader, code: "invalid_csv_header", status: http.StatusBadRequest},
+	{err: errDelimiter, code: "invalid_delimiter", status: http.StatusBadRequest},
+	{err: errLineTooLong, code: "line_too_long", status: http.StatusBadRequest},
+	{err: errStreamedOption, code: "not_streamable", status: http.StatusBadRequest},
+	{err: errFormat, code: "invalid_format", status: http.StatusBadRequest},
+
+	{err: errPinnedExcluded, code: "pinned_excluded", status: http.StatusUnprocessableEntity},
+	{err: errUnknownRequestID, code: "unknown_request_id", status: http.StatusUnprocessableEntity},
+	{err: errPinnedInvalid, code: "pinned_invalid", status: http.StatusUnprocessableEntity},
+	{err: errPinnedOverlap, code: "pinned_overlap", status: http.StatusUnprocessableEntity},
+	{err: errPinnedOutsideHorizon, code: "pinned_outside_horizon", status: http.StatusUnprocessableEntity},
+	{err: errNoRates, code: "no_exchange_rates", status: http.StatusUnprocessableEntity},
+	{err: fx.ErrUnknownCurrency, code: "unknown_currency", status: http.StatusUnprocessableEntity},
+	{err: errConvertedRateTooHigh, code: "converted_rate_too_high", status: http.StatusUnprocessableEntity},
+	{err: errDuplicateRequestID, code: "duplicate_request_id", status: http.StatusUnprocessableEntity},
+	{err: money.ErrRange, code: "amount_out_of_range", status: http.StatusUnprocessableEntity},
+
+	{err: errTooManyBookings, code: "too_many_bookings", status: http.StatusRequestEntityTooLarge},
+	{err: errNotAcceptable, code: "not_acceptable", status: http.StatusNotAcceptable},
+}
+
+// problemCode returns the code and HTTP status of err, internal_error and 500 for the errors of the server itself.
+func problemCode(err error) (string, int) {
+	for _, p := range problems {
+		if errors.Is(err, p.err) {
+			return p.code, p.status
+		}
+	}
+
+	var (
+		syntax    *json.SyntaxError
+		typeError *json.UnmarshalTypeError
+	)
+	if errors.As(err, &syntax) || errors.As(err, &typeError) {
+		return codeInvalidJSON, http.StatusBadRequest
+	}
+	return codeInternal, http.StatusInternalServerError
+}
+
+// newProblem returns the problem document of err for req, with the ID correlating it to the logs. The errors of the
+// server itself are not detailed to the client, the handler logs them under that ID.
+func newProblem(req *http.Request, correlationID string, err error) Problem {
+	code, status := problemCode(err)
+	p := Problem{
+		Type:          problemTypePrefix + code,
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        err.Error(),
+		Instance:      req.URL.Path,
+		Code:          code,
+		CorrelationID: correlationID,
+	}
+
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		p.Issues = validation.Issues
+	}
+	if status == http.StatusInternalServerError {
+		p.Detail = http.StatusText(status)
+	}
+	return p
+}
+
+// requestID returns the request ID sent by the client with req, or a new one when it sent none.
+func requestID(req *http.Request) string {
+	if id := req.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	return NewRequestID()
+}
+
+// NewRequestID returns a new random request ID, for the requests that come with none.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		// the clock still tells the requests apart.
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,192 @@
+//go:build unit
+
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/fx"
+	"github.com/xsolrac87/booking/money"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblemCode(t *testing.T) {
+	tests := map[error]struct {
+		code   string
+		status int
+	}{
+		errInvalidHttpMethod:    {code: "method_not_allowed", status: http.StatusMethodNotAllowed},
+		errNotFound:             {code: "not_found", status: http.StatusNotFound},
+		errRequestBody:          {code: "unreadable_body", status: http.StatusBadRequest},
+		errBodyTooLarge:         {code: "body_too_large", status: http.StatusRequestEntityTooLarge},
+		errInvalidRequestBody:   {code: "no_bookings", status: http.StatusBadRequest},
+		errInvalidQueryParam:    {code: "invalid_query_param", status: http.StatusBadRequest},
+		errValidationMode:       {code: "invalid_validation", status: http.StatusBadRequest},
+		errInvalidBookings:      {code: "invalid_bookings", status: http.StatusBadRequest},
+		errBookingType:          {code: "booking_not_object", status: http.StatusBadRequest},
+		errWrongType:            {code: "wrong_type", status: http.StatusBadRequest},
+		errRequestIDMissing:     {code: "request_id_missing", status: http.StatusBadRequest},
+		errCheckInMissing:       {code: "check_in_missing", status: http.StatusBadRequest},
+		errCheckInInvalid:       {code: "check_in_invalid", status: http.StatusBadRequest},
+		errNightsMissing:        {code: "nights_missing", status: http.StatusBadRequest},
+		errSellingRateMissing:   {code: "selling_rate_missing", status: http.StatusBadRequest},
+		errSellingRateInvalid:   {code: "selling_rate_invalid", status: http.StatusBadRequest},
+		errMarginMissing:        {code: "margin_missing", status: http.StatusBadRequest},
+		fx.ErrCurrency:          {code: "invalid_currency", status: http.StatusBadRequest},
+		errRooms:                {code: "invalid_rooms", status: http.StatusBadRequest},
+		errBufferNights:         {code: "invalid_buffer_nights", status: http.StatusBadRequest},
+		errObjective:            {code: "invalid_objective", status: http.StatusBadRequest},
+		errTop:                  {code: "invalid_top", status: http.StatusBadRequest},
+		errExplainTop:           {code: "explain_with_top", status: http.StatusBadRequest},
+		errFrontierRooms:        {code: "frontier_rooms", status: http.StatusBadRequest},
+		errHorizon:              {code: "invalid_horizon", status: http.StatusBadRequest},
+		errHorizonMode:          {code: "invalid_straddling", status: http.StatusBadRequest},
+		errGroupBy:              {code: "invalid_group_by", status: http.StatusBadRequest},
+		errBins:                 {code: "invalid_bins", status: http.StatusBadRequest},
+		errBinWidth:             {code: "invalid_bin_width", status: http.StatusBadRequest},
+		errBinEdges:             {code: "invalid_bin_edges", status: http.StatusBadRequest},
+		errQuantiles:            {code: "invalid_quantiles", status: http.StatusBadRequest},
+		errTooManyBuckets:       {code: "too_many_buckets", status: http.StatusBadRequest},
+		errCalendarSpan:         {code: "calendar_span_too_long", status: http.StatusBadRequest},
+		errDuplicatePolicy:      {code: "invalid_duplicates", status: http.StatusBadRequest},
+		errCSV:                  {code: "invalid_csv", status: http.StatusBadRequest},
+		errCSVHeader:            {code: "invalid_csv_header", status: http.StatusBadRequest},
+		errDelimiter:            {code: "invalid_delimiter", status: http.StatusBadRequest},
+		errLineTooLong:          {code: "line_too_long", status: http.StatusBadRequest},
+		errStreamedOption:       {code: "not_streamable", status: http.StatusBadRequest},
+		errFormat:               {code: "invalid_format", status: http.StatusBadRequest},
+		errPinnedExcluded:       {code: "pinned_excluded", status: http.StatusUnprocessableEntity},
+		errUnknownRequestID:     {code: "unknown_request_id", status: http.StatusUnprocessableEntity},
+		errPinnedInvalid:        {code: "pinned_invalid", status: http.StatusUnprocessableEntity},
+		errPinnedOverlap:        {code: "pinned_overlap", status: http.StatusUnprocessableEntity},
+		errPinnedOutsideHorizon: {code: "pinned_outside_horizon", status: http.StatusUnprocessableEntity},
+		errNoRates:              {code: "no_exchange_rates", status: http.StatusUnprocessableEntity},
+		fx.ErrUnknownCurrency:   {code: "unknown_currency", status: http.StatusUnprocessableEntity},
+		errConvertedRateTooHigh: {code: "converted_rate_too_high", status: http.StatusUnprocessableEntity},
+		errDuplicateRequestID:   {code: "duplicate_request_id", status: http.StatusUnprocessableEntity},
+		money.ErrRange:          {code: "amount_out_of_range", status: http.StatusUnprocessableEntity},
+		errTooManyBookings:      {code: "too_many_bookings", status: http.StatusRequestEntityTooLarge},
+		errNotAcceptable:        {code: "not_acceptable", status: http.StatusNotAcceptable},
+	}
+
+	// every mapped error is listed above, so no code is added or changed without this test.
+	if len(problems) != len(tests) {
+		t.Errorf("got %d mapped errors, expected %d", len(problems), len(tests))
+	}
+	for err, expected := range tests {
+		code, status := problemCode(fmt.Errorf("wrapped: %w", err))
+		if code != expected.code || status != expected.status {
+			t.Errorf("%v got: %s %d, expected: %s %d", err, code, status, expected.code, expected.status)
+		}
+	}
+
+	var bookings []Booking
+	syntax := json.Unmarshal([]byte(`[{`), &bookings)
+	typeError := json.Unmarshal([]byte(`{}`), &bookings)
+	for _, err := range []error{syntax, typeError} {
+		code, status := problemCode(err)
+		if code != codeInvalidJSON || status != http.StatusBadRequest {
+			t.Errorf("%v got: %s %d, expected: %s %d", err, code, status, codeInvalidJSON, http.StatusBadRequest)
+		}
+	}
+
+	code, status := problemCode(errors.New("disk on fire"))
+	if code != codeInternal || status != http.StatusInternalServerError {
+		t.Errorf("got: %s %d, expected: %s %d", code, status, codeInternal, http.StatusInternalServerError)
+	}
+}
+
+func TestHandler_SendErrorResponse(t *testing.T) {
+	tests := map[string]struct {
+		err           error
+		requestID     string
+		expected      Problem
+		expectedNewID bool
+	}{
+		"client error": {
+			err:       errMarginMissing,
+			requestID: "abc-123",
+			expected: Problem{
+				Type:          "urn:booking:problem:margin_missing",
+				Title:         "Bad Request",
+				Status:        http.StatusBadRequest,
+				Detail:        errMarginMissing.Error(),
+				Instance:      "/stats",
+				Code:          "margin_missing",
+				CorrelationID: "abc-123",
+			},
+		},
+		"validation issues": {
+			err:       &ValidationError{Issues: []Issue{{Index: 1, Field: "nights", Reason: ReasonMissing, Message: errNightsMissing.Error()}}},
+			requestID: "abc-123",
+			expected: Problem{
+				Type:          "urn:booking:problem:invalid_bookings",
+				Title:         "Bad Request",
+				Status:        http.StatusBadRequest,
+				Detail:        "payload contains invalid bookings: 1 issues",
+				Instance:      "/stats",
+				Code:          "invalid_bookings",
+				CorrelationID: "abc-123",
+				Issues:        []Issue{{Index: 1, Field: "nights", Reason: ReasonMissing, Message: errNightsMissing.Error()}},
+			},
+		},
+		"server error is not detailed": {
+			err: errors.New("disk on fire"),
+			expected: Problem{
+				Type:     "urn:booking:problem:internal_error",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "Internal Server Error",
+				Instance: "/stats",
+				Code:     "internal_error",
+			},
+			expectedNewID: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+
+			newHandler(t).sendErrorResponse(wr, req, tt.err)
+			if wr.Code != tt.expected.Status {
+				t.Errorf("got HTTP status code %d, expected %d", wr.Code, tt.expected.Status)
+			}
+			if ct := wr.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("got content type %s, expected %s", ct, problemContentType)
+			}
+
+			var got Problem
+			err := json.Unmarshal(wr.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expectedNewID {
+				if len(got.CorrelationID) != 32 {
+					t.Errorf("got correlation ID %q, expected a new one", got.CorrelationID)
+				}
+				tt.expected.CorrelationID = got.CorrelationID
+			}
+			if req.Header.Get(RequestIDHeader) != tt.requestID {
+				t.Errorf("got request %s header %q, expected it untouched", RequestIDHeader, req.Header.Get(RequestIDHeader))
+			}
+			if wr.Header().Get(RequestIDHeader) != got.CorrelationID {
+				t.Errorf("got %s header %q, expected %q", RequestIDHeader, wr.Header().Get(RequestIDHeader), got.CorrelationID)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,30 +1,82 @@
 package booking
 
 import (
-	"github.com/xsolrac87/booking/floatrounder"
+	"github.com/xsolrac87/booking/money"
+)
+
+const (
+	// profitRounding rounds the profit of a booking to the cent, ties to even so rounding errors cancel out over many
+	// bookings.
+	profitRounding = money.HalfEven
+	// statsRounding rounds profits per night and the stats on them to the cent, ties away from zero.
+	statsRounding = money.HalfUp
 )
 
 type ProfitPerNight struct {
-	AvgNight float64 `json:"avg_night"`
-	MinNight float64 `json:"min_night"`
-	MaxNight float64 `json:"max_night"`
+	AvgNight money.Amount `json:"avg_night"`
+	MinNight money.Amount `json:"min_night"`
+	MaxNight money.Amount `json:"max_night"`
 }
 
-func NewProfitPerNight(avg, min, max float64) ProfitPerNight {
+func NewProfitPerNight(avg, min, max money.Amount) ProfitPerNight {
 	return ProfitPerNight{
-		AvgNight: floatrounder.ToNearest(avg),
-		MinNight: floatrounder.ToNearest(min),
-		MaxNight: floatrounder.ToNearest(max),
+		AvgNight: avg,
+		MinNight: min,
+		MaxNight: max,
+	}
+}
+
+// Summary holds the profit per night stats, along with their distribution and groups when requested.
+type Summary struct {
+	ProfitPerNight
+	*Distribution
+	Groups map[GroupBy][]Group `json:"groups,omitempty"`
+	Reporting
+}
+
+// Distribution describes how the profit per night spreads across the valid bookings. StdDevNight is the population
+// standard deviation. Skipped counts the bookings left out: unreadable under lenient validation, dropped as duplicates,
+// invalid or outside the planning horizon.
+type Distribution struct {
+	MedianNight money.Amount `json:"median_night"`
+	P90Night    money.Amount `json:"p90_night"`
+	P95Night    money.Amount `json:"p95_night"`
+	P99Night    money.Amount `json:"p99_night"`
+	StdDevNight money.Amount `json:"stddev_night"`
+	Valid       int          `json:"valid"`
+	Skipped     int          `json:"skipped"`
+}
+
+func NewDistribution(median, p90, p95, p99, stdDev money.Amount, valid, skipped int) Distribution {
+	return Distribution{
+		MedianNight: median,
+		P90Night:    p90,
+		P95Night:    p95,
+		P99Night:    p99,
+		StdDevNight: stdDev,
+		Valid:       valid,
+		Skipped:     skipped,
 	}
 }
 
 type MaximizeProfit struct {
-	RequestIDS  []string `json:"request_ids"`
-	TotalProfit int32    `json:"total_profit"`
+	RequestIDS  []string     `json:"request_ids"`
+	TotalProfit money.Amount `json:"total_profit"`
 	ProfitPerNight
+	Objective      Objective         `json:"objective"`
+	ObjectiveValue money.Amount      `json:"objective_value"`
+	Rooms          []RoomAssignment  `json:"rooms,omitempty"`
+	Explanation    []BookingDecision `json:"explanation,omitempty"`
+	Reporting
 }
 
-func NewMaximizeProfit(ids []string, total int32, night ProfitPerNight) MaximizeProfit {
+// RoomAssignment lists the request IDs sold on a given room, numbered from 1.
+type RoomAssignment struct {
+	Room       int      `json:"room"`
+	RequestIDS []string `json:"request_ids"`
+}
+
+func NewMaximizeProfit(ids []string, total money.Amount, night ProfitPerNight) MaximizeProfit {
 	return MaximizeProfit{
 		RequestIDS:     ids,
 		TotalProfit:    total,
@@ -32,11 +84,14 @@ func NewMaximizeProfit(ids []string, total int32, night ProfitPerNight) Maximize
 	}
 }
 
-func profit(sellingRate, margin int32) int32 {
-	return sellingRate * margin / 100
+func profit(sellingRate money.Amount, margin int32) money.Amount {
+	return sellingRate.MulDiv(int64(margin), 100, profitRounding)
 }
 
-// it'll be wise to call profit to no replicate code, but dealing with floats could lead to wrong accuracy.
-func profitPerNight(sellingRate, margin, nights int32) float64 {
-	return (float64(sellingRate) * float64(margin) / 100) / float64(nights)
+// profitPerNight is not the rounded profit split over the nights, but the exact one rounded once.
+func profitPerNight(sellingRate money.Amount, margin, nights int32) money.Amount {
+	if nights == 0 {
+		return 0
+	}
+	return sellingRate.MulDiv(int64(margin), 100*int64(nights), statsRounding)
 }
@@ -2,23 +2,46 @@
 
 package booking
 
-import "testing"
+import (
+	"github.com/xsolrac87/booking/money"
+	"testing"
+)
 
 func TestProfit(t *testing.T) {
 	tests := map[string]struct {
-		sellingRate int32
+		sellingRate money.Amount
 		margin      int32
-		expected    int32
+		expected    money.Amount
 	}{
 		"profit with selling rate 200 and margin 20": {
-			sellingRate: 200,
+			sellingRate: money.FromUnits(200),
 			margin:      20,
-			expected:    40,
+			expected:    money.FromUnits(40),
 		},
 		"profit with selling rate 50 and margin 5": {
-			sellingRate: 50,
+			sellingRate: money.FromUnits(50),
 			margin:      5,
-			expected:    2,
+			expected:    money.MustParse("2.5"),
+		},
+		"profit with selling rate 199.99 and margin 15": {
+			sellingRate: money.MustParse("199.99"),
+			margin:      15,
+			expected:    money.MustParse("30"),
+		},
+		"profit rounded half to even": {
+			sellingRate: money.MustParse("0.5"),
+			margin:      5,
+			expected:    money.MustParse("0.02"),
+		},
+		"profit above int32 rates": {
+			sellingRate: money.FromUnits(maxSellingRate),
+			margin:      100,
+			expected:    money.FromUnits(maxSellingRate),
+		},
+		"profit of the highest rate and margin": {
+			sellingRate: money.FromUnits(maxSellingRate),
+			margin:      maxMargin,
+			expected:    money.FromUnits(maxSellingRate * maxMargin / 100),
 		},
 	}
 	for name, tt := range tests {
@@ -27,7 +50,7 @@ func TestProfit(t *testing.T) {
 			t.Parallel()
 			got := profit(tt.sellingRate, tt.margin)
 			if got != tt.expected {
-				t.Errorf("got: %d, expected: %d", got, tt.expected)
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
 			}
 		})
 	}
@@ -35,22 +58,34 @@ func TestProfit(t *testing.T) {
 
 func TestProfitPerNight(t *testing.T) {
 	tests := map[string]struct {
-		sellingRate int32
+		sellingRate money.Amount
 		margin      int32
 		nights      int32
-		expected    float64
+		expected    money.Amount
 	}{
 		"profit per night 1": {
-			sellingRate: 200,
+			sellingRate: money.FromUnits(200),
 			margin:      20,
 			nights:      5,
-			expected:    8.0,
+			expected:    money.FromUnits(8),
 		},
 		"profit per night 2": {
-			sellingRate: 50,
+			sellingRate: money.FromUnits(50),
 			margin:      20,
 			nights:      1,
-			expected:    10.000000,
+			expected:    money.FromUnits(10),
+		},
+		"profit per night rounded half up": {
+			sellingRate: money.MustParse("0.5"),
+			margin:      5,
+			nights:      1,
+			expected:    money.MustParse("0.03"),
+		},
+		"profit per night rounded to the cent": {
+			sellingRate: money.FromUnits(100),
+			margin:      10,
+			nights:      3,
+			expected:    money.MustParse("3.33"),
 		},
 	}
 	for name, tt := range tests {
@@ -58,24 +93,29 @@ func TestProfitPerNight(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 			got := profitPerNight(tt.sellingRate, tt.margin, tt.nights)
-			// Using the != operator to compare two floating-point numbers can lead to inaccuracies ( mantissa )
-			// Instead, we should compare their difference to see if it is less than some small error value.
-			// This can be done with testify testing library and InDelta function. ( https://pkg.go.dev/github.com/stretchr/testify/assert?utm_source=godoc#InDelta )
 			if got != tt.expected {
-				t.Errorf("got: %f, expected: %f", got, tt.expected)
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
 			}
 		})
 	}
 }
 
 func FuzzProfit(f *testing.F) {
-	f.Fuzz(func(t *testing.T, rate, margin int32) {
-		profit(rate, margin)
+	f.Fuzz(func(t *testing.T, rate int64, margin int32) {
+		b := Booking{RequestID: "A", Nights: 1, SellingRate: money.FromMinor(rate), Margin: margin}
+		if !b.valid() {
+			t.Skip()
+		}
+		profit(b.SellingRate, b.Margin)
 	})
 }
 
 func FuzzPerNight(f *testing.F) {
-	f.Fuzz(func(t *testing.T, rate, margin, nights int32) {
-		profitPerNight(rate, margin, nights)
+	f.Fuzz(func(t *testing.T, rate int64, margin, nights int32) {
+		b := Booking{RequestID: "A", Nights: nights, SellingRate: money.FromMinor(rate), Margin: margin}
+		if !b.valid() {
+			t.Skip()
+		}
+		profitPerNight(b.SellingRate, b.Margin, b.Nights)
 	})
 }
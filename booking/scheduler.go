@@ -0,0 +1,612 @@
+package booking
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	pinnedWeight = iota
+	objectiveWeight
+	profitWeight
+	bookingsWeight
+	checkInWeight
+	weightSize
+)
+
+// weight is what a schedule maximises, compared lexicographically: first how many of the forced stays it holds,
+// so they are always taken when possible, then the objective, the profit, the fewest stays (each one weighing -1)
+// and the earliest check-ins (each one weighing minus its day).
+type weight [weightSize]int64
+
+func (w weight) add(o weight) weight {
+	for i := range w {
+		w[i] += o[i]
+	}
+	return w
+}
+
+func (w weight) sub(o weight) weight {
+	for i := range w {
+		w[i] -= o[i]
+	}
+	return w
+}
+
+func (w weight) neg() weight {
+	return weight{}.sub(w)
+}
+
+func (w weight) less(o weight) bool {
+	for i := range w {
+		if w[i] != o[i] {
+			return w[i] < o[i]
+		}
+	}
+	return false
+}
+
+// stay is a valid booking placed on a timeline of days. It blocks its unit from start (included) to end (excluded),
+// that is, its nights plus the buffer nights the unit needs before the next check-in, so two stays conflict when
+// their ranges intersect.
+type stay struct {
+	booking    Booking
+	start, end int64
+	weight     weight
+}
+
+func newStay(b Booking, bufferNights int) stay {
+	start := dayNumber(b.CheckIn)
+	return stay{
+		booking: b,
+		start:   start,
+		end:     start + int64(b.Nights) + int64(bufferNights),
+		weight: weight{
+			profitWeight:   profit(b.SellingRate, b.Margin).Minor(),
+			bookingsWeight: -1,
+			checkInWeight:  -start,
+		},
+	}
+}
+
+func (s stay) overlaps(o stay) bool {
+	return s.start < o.end && o.start < s.end
+}
+
+// dayNumber returns the number of days between the unix epoch and t, rounding towards the past.
+func dayNumber(t time.Time) int64 {
+	const secondsPerDay = 24 * 60 * 60
+	sec := t.Unix()
+	days := sec / secondsPerDay
+	if sec%secondsPerDay < 0 {
+		days--
+	}
+	return days
+}
+
+// problem is a scheduling of stays over rooms where some stays can be forced in or out of the schedule, on top of
+// the ones pinned or excluded by the request.
+type problem struct {
+	stays            []stay
+	rooms            int
+	pinned, excluded []bool
+	objective        Objective
+	horizon          *horizon
+	conversion       *conversion
+}
+
+// checkRange returns money.ErrRange when the weights of the stays could overflow once combined by the solver: summed
+// for the additive objectives, and scaled by the number of stays for the average one. Valid bookings never weigh
+// less than zero on the objective and the profit.
+func (p problem) checkRange() error {
+	var totals weight
+	for _, s := range p.stays {
+		for _, i := range []int{objectiveWeight, profitWeight} {
+			if totals[i] > math.MaxInt64-s.weight[i] {
+				return fmt.Errorf("%w: the bookings are worth too much to be combined", money.ErrRange)
+			}
+			totals[i] += s.weight[i]
+		}
+	}
+
+	if !p.objective.additive() {
+		// every stay weighs up to |S| times its objective plus the sum over S, and a schedule sums them.
+		hi, lo := bits.Mul64(2*uint64(len(p.stays)), uint64(totals[objectiveWeight]))
+		if hi != 0 || lo > math.MaxInt64 {
+			return fmt.Errorf("%w: the bookings are worth too much to be averaged", money.ErrRange)
+		}
+	}
+	return nil
+}
+
+// solve returns the indexes of the best schedule holding every included stay and none of the excluded ones,
+// or false when the included stays do not fit together.
+func (p problem) solve(included, excluded []bool) ([]int, bool) {
+	if p.objective.additive() {
+		return p.schedule(included, excluded, 1, 0)
+	}
+	return p.scheduleAverage(included, excluded)
+}
+
+// scheduleAverage maximises the average objective of the stays with Dinkelbach's method: the best average a is
+// the one for which no schedule has a positive sum of (objective - a). Starting from any schedule S with average
+// sum(S)/|S|, the schedule maximising the sum of (|S| * objective - sum(S)) either averages strictly more, and
+// becomes the new S, or sums zero, and then S is optimal. Scaling by |S| keeps every weight an exact integer.
+func (p problem) scheduleAverage(included, excluded []bool) ([]int, bool) {
+	selected, ok := p.schedule(included, excluded, 1, 0)
+	if !ok || len(selected) == 0 {
+		return selected, ok
+	}
+
+	for {
+		var (
+			scale = int64(len(selected))
+			shift int64
+		)
+		for _, i := range selected {
+			shift += p.stays[i].weight[objectiveWeight]
+		}
+		next, _ := p.schedule(included, excluded, scale, shift)
+
+		var gain int64
+		for _, i := range next {
+			gain += scale*p.stays[i].weight[objectiveWeight] - shift
+		}
+		switch {
+		case gain < 0 || len(next) == 0:
+			return selected, true
+		case gain == 0:
+			// as good on average, and at least as good on the tie-breakers the weights carry after the objective
+			return next, true
+		}
+		selected = next
+	}
+}
+
+// schedule returns the best schedule, like solve, for stays weighing scale * objective - shift on the objective.
+func (p problem) schedule(included, excluded []bool, scale, shift int64) ([]int, bool) {
+	var (
+		stays   = make([]stay, 0, len(p.stays))
+		indexes = make([]int, 0, len(p.stays))
+		forced  int
+	)
+	for i, s := range p.stays {
+		if excluded[i] {
+			continue
+		}
+		if included[i] {
+			s.weight[pinnedWeight] = 1
+			forced++
+		}
+		s.weight[objectiveWeight] = scale*s.weight[objectiveWeight] - shift
+		stays = append(stays, s)
+		indexes = append(indexes, i)
+	}
+
+	var selected []int
+	if p.rooms == 1 {
+		selected = scheduleSingle(stays)
+	} else {
+		selected = scheduleRooms(stays, p.rooms)
+	}
+
+	for i, idx := range selected {
+		if stays[idx].weight[pinnedWeight] > 0 {
+			forced--
+		}
+		selected[i] = indexes[idx]
+	}
+	return selected, forced == 0
+}
+
+// constraints returns copies of the stays pinned and excluded by the request, ready to force more of them.
+func (p problem) constraints() ([]bool, []bool) {
+	included := make([]bool, len(p.stays))
+	excluded := make([]bool, len(p.stays))
+	copy(included, p.pinned)
+	copy(excluded, p.excluded)
+	return included, excluded
+}
+
+// best returns the indexes of the best schedule honouring the request, or an error when pinned stays do not fit.
+func (p problem) best() ([]int, error) {
+	selected, ok := p.solve(p.constraints())
+	if !ok {
+		return nil, errPinnedOverlap
+	}
+	return selected, nil
+}
+
+// top returns up to k distinct schedules, the best one first, following Lawler's partitioning: once the best
+// schedule of a subspace is known, the rest of the subspace splits, for every free stay in turn, into the schedules
+// agreeing with it on the previous free stays and disagreeing on that one. Each part is solved and queued.
+func (p problem) top(k int) [][]int {
+	var (
+		n       = len(p.stays)
+		results [][]int
+		queue   = &subspaceQueue{}
+	)
+
+	push := func(included, excluded []bool) {
+		selected, ok := p.solve(included, excluded)
+		if !ok {
+			return
+		}
+		heap.Push(queue, subspace{
+			included: included,
+			excluded: excluded,
+			selected: selected,
+			weight:   p.score(selected),
+			sequence: queue.sequence,
+		})
+		queue.sequence++
+	}
+
+	push(p.constraints())
+	for len(results) < k && queue.Len() > 0 {
+		// an empty schedule is no combination to offer, but the subspace may still hold worthless stays to branch on
+		best := heap.Pop(queue).(subspace)
+		if len(best.selected) > 0 {
+			results = append(results, best.selected)
+		}
+
+		taken := make([]bool, n)
+		for _, i := range best.selected {
+			taken[i] = true
+		}
+
+		included := append([]bool(nil), best.included...)
+		excluded := append([]bool(nil), best.excluded...)
+		for i := 0; i < n; i++ {
+			if included[i] || excluded[i] {
+				continue
+			}
+
+			branchIncluded := append([]bool(nil), included...)
+			branchExcluded := append([]bool(nil), excluded...)
+			if taken[i] {
+				branchExcluded[i] = true
+				included[i] = true
+			} else {
+				branchIncluded[i] = true
+				excluded[i] = true
+			}
+			push(branchIncluded, branchExcluded)
+		}
+	}
+	return results
+}
+
+// overlapping returns the request IDs of the selected stays overlapping the i-th one, by check-in.
+func (p problem) overlapping(i int, selected []int) []string {
+	var overlaps []stay
+	for _, j := range selected {
+		if j != i && p.stays[j].overlaps(p.stays[i]) {
+			overlaps = append(overlaps, p.stays[j])
+		}
+	}
+	sort.SliceStable(overlaps, func(a, b int) bool {
+		return overlaps[a].start < overlaps[b].start
+	})
+
+	ids := make([]string, 0, len(overlaps))
+	for _, s := range overlaps {
+		ids = append(ids, s.booking.RequestID)
+	}
+	return ids
+}
+
+// score returns how good the schedule of the selected stays is, comparable with the score of any other schedule.
+// With an average objective, the objective is the average of the selected stays, rounded down.
+func (p problem) score(selected []int) weight {
+	var w weight
+	for _, i := range selected {
+		w = w.add(p.stays[i].weight)
+	}
+	if !p.objective.additive() && len(selected) > 0 {
+		w[objectiveWeight] = floorDiv(w[objectiveWeight], int64(len(selected)))
+	}
+	return w
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// scheduleSingle returns the indexes of the non-overlapping stays with the highest total weight for a single unit.
+// Once stays are sorted by end, the best schedule of the first j stays either skips the j-th one or takes it on top
+// of the best schedule of the stays ending before it starts, found with a binary search, so it runs in O(n log n).
+func scheduleSingle(stays []stay) []int {
+	order := make([]int, len(stays))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return stays[order[i]].end < stays[order[j]].end
+	})
+
+	var (
+		best     = make([]weight, len(order)+1) // best[j] is the max total weight using the first j stays
+		taken    = make([]bool, len(order))
+		previous = make([]int, len(order))
+	)
+
+	for j, idx := range order {
+		start := stays[idx].start
+		previous[j] = sort.Search(j, func(i int) bool {
+			return stays[order[i]].end > start
+		})
+
+		best[j+1] = best[j]
+		if with := best[previous[j]].add(stays[idx].weight); best[j].less(with) {
+			best[j+1] = with
+			taken[j] = true
+		}
+	}
+
+	var selected []int
+	for j := len(order); j > 0; {
+		if !taken[j-1] {
+			j--
+			continue
+		}
+		selected = append(selected, order[j-1])
+		j = previous[j-1]
+	}
+	return selected
+}
+
+// scheduleRooms returns the indexes of the stays with the highest total weight such that no more than rooms of them
+// overlap on any day. It's solved as a min-cost flow: rooms units of flow travel along the timeline, and every stay
+// is a shortcut from its start to its end that a single unit can take, earning its profit.
+func scheduleRooms(stays []stay, rooms int) []int {
+	if len(stays) == 0 {
+		return nil
+	}
+
+	points := make([]int64, 0, 2*len(stays))
+	for _, s := range stays {
+		points = append(points, s.start, s.end)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i] < points[j]
+	})
+
+	nodes := make(map[int64]int, len(points))
+	for _, p := range points {
+		if _, ok := nodes[p]; !ok {
+			nodes[p] = len(nodes)
+		}
+	}
+
+	g := newFlowGraph(len(nodes))
+	for i := 0; i+1 < len(nodes); i++ {
+		g.addEdge(i, i+1, rooms, weight{})
+	}
+
+	edges := make([]int, len(stays))
+	for i, s := range stays {
+		edges[i] = g.addEdge(nodes[s.start], nodes[s.end], 1, s.weight.neg())
+	}
+
+	g.minCostFlow(0, len(nodes)-1, rooms)
+
+	var selected []int
+	for i, e := range edges {
+		if g.edges[e].flow > 0 {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// assignRooms spreads stays, none of them overlapping more than rooms times, over rooms numbered from 1.
+// Taking stays by start and giving each one the lowest free room never runs out of rooms on an interval graph.
+func assignRooms(stays []stay, rooms int) []RoomAssignment {
+	sorted := make([]stay, len(stays))
+	copy(sorted, stays)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].start < sorted[j].start
+	})
+
+	var (
+		assignments = make([]RoomAssignment, rooms)
+		freeFrom    = make([]int64, rooms)
+		occupied    = make([]bool, rooms)
+	)
+	for r := range assignments {
+		assignments[r] = RoomAssignment{Room: r + 1, RequestIDS: []string{}}
+	}
+
+	for _, s := range sorted {
+		for r := range assignments {
+			if occupied[r] && freeFrom[r] > s.start {
+				continue
+			}
+			assignments[r].RequestIDS = append(assignments[r].RequestIDS, s.booking.RequestID)
+			freeFrom[r] = s.end
+			occupied[r] = true
+			break
+		}
+	}
+	return assignments
+}
+
+type flowEdge struct {
+	to, capacity, flow int
+	cost               weight
+}
+
+// flowGraph is a residual graph where every edge is stored next to its reverse one, so e^1 is the reverse of e.
+type flowGraph struct {
+	edges     []flowEdge
+	adjacency [][]int
+}
+
+func newFlowGraph(nodes int) *flowGraph {
+	return &flowGraph{adjacency: make([][]int, nodes)}
+}
+
+func (g *flowGraph) addEdge(from, to, capacity int, cost weight) int {
+	g.adjacency[from] = append(g.adjacency[from], len(g.edges))
+	g.edges = append(g.edges, flowEdge{to: to, capacity: capacity, cost: cost})
+	g.adjacency[to] = append(g.adjacency[to], len(g.edges))
+	g.edges = append(g.edges, flowEdge{to: from, cost: cost.neg()})
+	return len(g.edges) - 2
+}
+
+func (g *flowGraph) residual(e int) int {
+	return g.edges[e].capacity - g.edges[e].flow
+}
+
+// minCostFlow sends up to limit units from source to sink with successive shortest paths, stopping as soon as one
+// more unit would not lower the total cost. Every edge of the initial graph goes from a lower to a higher node, so
+// potentials start as the shortest distances over that DAG and Dijkstra can run on non-negative reduced costs.
+func (g *flowGraph) minCostFlow(source, sink, limit int) {
+	n := len(g.adjacency)
+	potential := make([]weight, n)
+	reached := make([]bool, n)
+	reached[source] = true
+	for u := 0; u < n; u++ {
+		if !reached[u] {
+			continue
+		}
+		for _, e := range g.adjacency[u] {
+			edge := g.edges[e]
+			if d := potential[u].add(edge.cost); g.residual(e) > 0 && (!reached[edge.to] || d.less(potential[edge.to])) {
+				potential[edge.to] = d
+				reached[edge.to] = true
+			}
+		}
+	}
+
+	for sent := 0; sent < limit; {
+		dist, via, ok := g.shortestPaths(source, potential)
+		if !ok[sink] || !dist[sink].add(potential[sink]).sub(potential[source]).less(weight{}) {
+			return
+		}
+
+		units := limit - sent
+		for v := sink; v != source; v = g.edges[via[v]^1].to {
+			if r := g.residual(via[v]); r < units {
+				units = r
+			}
+		}
+		for v := sink; v != source; v = g.edges[via[v]^1].to {
+			g.edges[via[v]].flow += units
+			g.edges[via[v]^1].flow -= units
+		}
+		sent += units
+
+		for v := range potential {
+			if ok[v] {
+				potential[v] = potential[v].add(dist[v])
+			}
+		}
+	}
+}
+
+func (g *flowGraph) shortestPaths(source int, potential []weight) ([]weight, []int, []bool) {
+	var (
+		n    = len(g.adjacency)
+		dist = make([]weight, n)
+		via  = make([]int, n)
+		ok   = make([]bool, n)
+		done = make([]bool, n)
+		pq   = &nodeQueue{{node: source}}
+	)
+	ok[source] = true
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(queuedNode)
+		if done[item.node] {
+			continue
+		}
+		done[item.node] = true
+
+		for _, e := range g.adjacency[item.node] {
+			if g.residual(e) <= 0 {
+				continue
+			}
+			edge := g.edges[e]
+			d := item.dist.add(edge.cost).add(potential[item.node]).sub(potential[edge.to])
+			if !ok[edge.to] || d.less(dist[edge.to]) {
+				dist[edge.to] = d
+				via[edge.to] = e
+				ok[edge.to] = true
+				heap.Push(pq, queuedNode{node: edge.to, dist: d})
+			}
+		}
+	}
+	return dist, via, ok
+}
+
+type queuedNode struct {
+	node int
+	dist weight
+}
+
+type nodeQueue []queuedNode
+
+func (q nodeQueue) Len() int { return len(q) }
+
+func (q nodeQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist.less(q[j].dist)
+	}
+	return q[i].node < q[j].node
+}
+
+func (q nodeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *nodeQueue) Push(x any) { *q = append(*q, x.(queuedNode)) }
+
+func (q *nodeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// subspace is a part of Lawler's partitioning, with the best schedule found inside it.
+type subspace struct {
+	included, excluded []bool
+	selected           []int
+	weight             weight
+	sequence           int
+}
+
+// subspaceQueue pops the subspace with the best schedule first, and the earliest queued one on ties.
+type subspaceQueue struct {
+	items    []subspace
+	sequence int
+}
+
+func (q subspaceQueue) Len() int { return len(q.items) }
+
+func (q subspaceQueue) Less(i, j int) bool {
+	if a, b := q.items[i].weight, q.items[j].weight; a != b {
+		return b.less(a)
+	}
+	return q.items[i].sequence < q.items[j].sequence
+}
+
+func (q subspaceQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *subspaceQueue) Push(x any) { q.items = append(q.items, x.(subspace)) }
+
+func (q *subspaceQueue) Pop() any {
+	old := q.items
+	item := old[len(old)-1]
+	q.items = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,150 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDayNumber(t *testing.T) {
+	tests := map[string]struct {
+		date     string
+		expected int64
+	}{
+		"epoch": {
+			date:     "1970-01-01",
+			expected: 0,
+		},
+		"after epoch": {
+			date:     "1970-01-11",
+			expected: 10,
+		},
+		"before epoch": {
+			date:     "1969-12-31",
+			expected: -1,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := dayNumber(parse(tt.date))
+			if got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStay_Overlaps(t *testing.T) {
+	tests := map[string]struct {
+		a, b         Booking
+		bufferNights int
+		expected     bool
+	}{
+		"same check in": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-01"), Nights: 1},
+			bufferNights: defaultBufferNights,
+			expected:     true,
+		},
+		"check in on check out day": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-03"), Nights: 1},
+			bufferNights: defaultBufferNights,
+			expected:     true,
+		},
+		"check in the day after check out": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-04"), Nights: 1},
+			bufferNights: defaultBufferNights,
+			expected:     false,
+		},
+		"same day turnover": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-03"), Nights: 1},
+			bufferNights: 0,
+			expected:     false,
+		},
+		"check in within the cleaning buffer": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-04"), Nights: 1},
+			bufferNights: 2,
+			expected:     true,
+		},
+		"check in after the cleaning buffer": {
+			a:            Booking{CheckIn: parse("2023-01-01"), Nights: 2},
+			b:            Booking{CheckIn: parse("2023-01-05"), Nights: 1},
+			bufferNights: 2,
+			expected:     false,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			a, b := newStay(tt.a, tt.bufferNights), newStay(tt.b, tt.bufferNights)
+			if got := a.overlaps(b); got != tt.expected {
+				t.Errorf("got: %t, expected: %t", got, tt.expected)
+			}
+			if got := b.overlaps(a); got != tt.expected {
+				t.Errorf("got: %t, expected: %t", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAssignRooms(t *testing.T) {
+	stays := []stay{
+		newStay(Booking{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 3}, defaultBufferNights),
+		newStay(Booking{RequestID: "B", CheckIn: parse("2023-01-02"), Nights: 5}, defaultBufferNights),
+		newStay(Booking{RequestID: "C", CheckIn: parse("2023-01-05"), Nights: 1}, defaultBufferNights),
+		newStay(Booking{RequestID: "D", CheckIn: parse("2023-01-08"), Nights: 1}, defaultBufferNights),
+	}
+	expected := []RoomAssignment{
+		{Room: 1, RequestIDS: []string{"A", "C", "D"}},
+		{Room: 2, RequestIDS: []string{"B"}},
+		{Room: 3, RequestIDS: []string{}},
+	}
+
+	got := assignRooms(stays, 3)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got: %v, expected: %v", got, expected)
+	}
+}
+
+func TestProblem_CheckRange(t *testing.T) {
+	worth := func(values ...int64) []stay {
+		stays := make([]stay, 0, len(values))
+		for _, v := range values {
+			stays = append(stays, stay{weight: weight{objectiveWeight: v, profitWeight: v}})
+		}
+		return stays
+	}
+
+	tests := map[string]struct {
+		objective   Objective
+		stays       []stay
+		expectedErr error
+	}{
+		"sum in range":         {objective: ObjectiveProfit, stays: worth(math.MaxInt64/2, math.MaxInt64/2)},
+		"sum out of range":     {objective: ObjectiveProfit, stays: worth(math.MaxInt64/2, math.MaxInt64/2, 2), expectedErr: money.ErrRange},
+		"average in range":     {objective: ObjectiveAvgProfitPerNight, stays: worth(math.MaxInt64/8, math.MaxInt64/8)},
+		"average out of range": {objective: ObjectiveAvgProfitPerNight, stays: worth(math.MaxInt64/4, math.MaxInt64/4), expectedErr: money.ErrRange},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			p := problem{objective: tt.objective, stays: tt.stays}
+			if err := p.checkRange(); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
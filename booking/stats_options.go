@@ -0,0 +1,114 @@
+package booking
+
+import (
+	"github.com/xsolrac87/booking/fx"
+	"log/slog"
+	"time"
+)
+
+type statsOptions struct {
+	horizon      *horizon
+	distribution bool
+	groupBy      []GroupBy
+	rates        fx.RateProvider
+	currency     string
+	conversion   *conversion
+	duplicates   DuplicatePolicy
+	logger       *slog.Logger
+	unread       int
+}
+
+type StatsOption func(options *statsOptions) error
+
+// WithStatsHorizon only takes into account the nights from and to, both included, a zero bound leaving that side
+// open. Mode tells what to do with the stays straddling a bound, leaving them out by default.
+func WithStatsHorizon(from, to time.Time, mode HorizonMode) StatsOption {
+	return func(options *statsOptions) error {
+		h, err := newHorizon(from, to, mode)
+		if err != nil {
+			return err
+		}
+		options.horizon = h
+		return nil
+	}
+}
+
+// WithDistribution adds the median, p90, p95 and p99 percentiles, the standard deviation and how many bookings were
+// taken into account or skipped to the stats.
+func WithDistribution() StatsOption {
+	return func(options *statsOptions) error {
+		options.distribution = true
+		return nil
+	}
+}
+
+// WithGroupBy adds the profit per night stats of every group along each of the given dimensions.
+func WithGroupBy(dimensions ...GroupBy) StatsOption {
+	return func(options *statsOptions) error {
+		for _, d := range dimensions {
+			if !d.valid() {
+				return errGroupBy
+			}
+		}
+		options.groupBy = append(options.groupBy, dimensions...)
+		return nil
+	}
+}
+
+// WithStatsRates normalises every booking to the reporting currency with the exchange rates of provider, before
+// computing any profit.
+func WithStatsRates(provider fx.RateProvider) StatsOption {
+	return func(options *statsOptions) error {
+		options.rates = provider
+		return nil
+	}
+}
+
+// WithStatsCurrency sets the currency the stats are reported in, the base currency of the exchange rates by default.
+func WithStatsCurrency(currency string) StatsOption {
+	return func(options *statsOptions) error {
+		if !fx.ValidCurrency(currency) {
+			return fx.ErrCurrency
+		}
+		options.currency = currency
+		return nil
+	}
+}
+
+// WithStatsDuplicates sets which booking to take into account when several share a request ID, all of them by default.
+func WithStatsDuplicates(policy DuplicatePolicy) StatsOption {
+	return func(options *statsOptions) error {
+		if !policy.valid() {
+			return errDuplicatePolicy
+		}
+		options.duplicates = policy
+		return nil
+	}
+}
+
+// WithStatsLogger sets the logger the operations are logged with, at debug level, slog.Default() by default.
+func WithStatsLogger(logger *slog.Logger) StatsOption {
+	return func(options *statsOptions) error {
+		if logger == nil {
+			return errLogger
+		}
+		options.logger = logger
+		return nil
+	}
+}
+
+func (o statsOptions) loggerOrDefault() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
+
+// withUnread counts as skipped the n bookings of the payload left out as they could not be read, before the service
+// got the others.
+func withUnread(n int) StatsOption {
+	return func(options *statsOptions) error {
+		options.unread = n
+		return nil
+	}
+}
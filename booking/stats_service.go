@@ -1,15 +1,105 @@
 package booking
 
-type StatsService struct{}
+import (
+	"github.com/xsolrac87/booking/money"
+	"math"
+	"time"
+)
 
-func NewStatsService() (*StatsService, error) {
-	return &StatsService{}, nil
+type StatsService struct {
+	defaults []StatsOption
+}
+
+// NewStatsService returns a service applying opts as defaults to every request, before the request's own options.
+func NewStatsService(opts ...StatsOption) (*StatsService, error) {
+	var options statsOptions
+	for _, opt := range opts {
+		err := opt(&options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &StatsService{defaults: opts}, nil
+}
+
+// Summarize returns the profit per night stats of the valid bookings, under the given options.
+func (s *StatsService) Summarize(bookings []Booking, opts ...StatsOption) (Summary, error) {
+	options, err := s.options(opts)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer logOperation(options.loggerOrDefault(), "summarize", time.Now())
+	// the bookings dropped as duplicates, as well as the unread ones, are skipped too.
+	received := options.unread + len(bookings)
+	bookings, err = options.duplicates.apply(bookings, options.conversion)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	// a single pass over the bookings feeds the stats and every group.
+	var (
+		profitPerNightList = make([]money.Amount, 0, len(bookings))
+		sumProfitPerNight  money.Amount
+		groups             = newGroups(options.groupBy)
+	)
+	for _, booking := range bookings {
+		booking, ok := options.horizon.clip(booking)
+		if !ok || !booking.valid() {
+			continue
+		}
+		booking, err = options.conversion.convert(booking)
+		if err != nil {
+			return Summary{}, err
+		}
+
+		p := profitPerNight(booking.SellingRate, booking.Margin, booking.Nights)
+		profitPerNightList = append(profitPerNightList, p)
+		sumProfitPerNight += p
+		err = groups.add(booking, p)
+		if err != nil {
+			return Summary{}, err
+		}
+	}
+
+	summary := Summary{
+		ProfitPerNight: s.profitPerNight(profitPerNightList, sumProfitPerNight),
+		Groups:         groups.list(),
+		Reporting:      options.conversion.reporting(),
+	}
+	if options.distribution {
+		d := s.distribution(profitPerNightList, sumProfitPerNight, received-len(profitPerNightList))
+		summary.Distribution = &d
+	}
+	return summary, nil
+}
+
+func (s *StatsService) options(opts []StatsOption) (statsOptions, error) {
+	var options statsOptions
+	for _, opt := range append(append([]StatsOption(nil), s.defaults...), opts...) {
+		err := opt(&options)
+		if err != nil {
+			return statsOptions{}, err
+		}
+	}
+
+	conversion, err := newConversion(options.rates, options.currency)
+	if err != nil {
+		return statsOptions{}, err
+	}
+	options.conversion = conversion
+	return options, nil
 }
 
 func (s *StatsService) ProfitPerNight(bookings []Booking) ProfitPerNight {
+	return s.profitPerNight(s.profitsPerNight(bookings))
+}
+
+// profitsPerNight returns the profit per night of every valid booking, in the order they came, and their sum.
+func (s *StatsService) profitsPerNight(bookings []Booking) ([]money.Amount, money.Amount) {
 	var (
-		profitPerNightList = make([]float64, 0, len(bookings))
-		sumProfitPerNight  float64
+		profitPerNightList = make([]money.Amount, 0, len(bookings))
+		sumProfitPerNight  money.Amount
 	)
 
 	for _, booking := range bookings {
@@ -19,16 +109,106 @@ func (s *StatsService) ProfitPerNight(bookings []Booking) ProfitPerNight {
 			sumProfitPerNight += p
 		}
 	}
+	return profitPerNightList, sumProfitPerNight
+}
 
-	var avgProfitPerNight float64
+func (s *StatsService) profitPerNight(profitPerNightList []money.Amount, sumProfitPerNight money.Amount) ProfitPerNight {
+	var avgProfitPerNight money.Amount
 	if len(profitPerNightList) > 0 {
-		avgProfitPerNight = sumProfitPerNight / float64(len(profitPerNightList))
+		avgProfitPerNight = sumProfitPerNight.MulDiv(1, int64(len(profitPerNightList)), statsRounding)
 	}
 
 	return NewProfitPerNight(avgProfitPerNight, s.min(profitPerNightList), s.max(profitPerNightList))
 }
 
-func (s *StatsService) min(list []float64) float64 {
+// distribution returns the median, percentiles and standard deviation of list, reordering it in place. The
+// percentiles interpolate linearly between the two closest ranks, in linear time by selecting those ranks instead
+// of sorting the whole list.
+func (s *StatsService) distribution(list []money.Amount, sum money.Amount, skipped int) Distribution {
+	if len(list) == 0 {
+		return NewDistribution(0, 0, 0, 0, 0, 0, skipped)
+	}
+
+	var (
+		mean     = float64(sum) / float64(len(list))
+		variance float64
+	)
+	for _, v := range list {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	variance /= float64(len(list))
+
+	// ranks go up, so each selection only needs to look past the previous one.
+	var (
+		percentiles = []int64{50, 90, 95, 99}
+		values      = make([]money.Amount, len(percentiles))
+		from        int
+	)
+	for i, p := range percentiles {
+		var (
+			rank     = p * int64(len(list)-1) // in hundredths
+			k        = int(rank / 100)
+			fraction = rank % 100
+		)
+		s.selectKth(list[from:], k-from)
+		from = k
+
+		values[i] = list[k]
+		if fraction > 0 {
+			next := s.min(list[k+1:])
+			values[i] += (next - list[k]).MulDiv(fraction, 100, statsRounding)
+		}
+	}
+
+	stdDev := money.FromFloat(math.Sqrt(variance)/money.Scale, statsRounding)
+	return NewDistribution(values[0], values[1], values[2], values[3], stdDev, len(list), skipped)
+}
+
+// selectKth reorders list so the value at k is the one it would hold if list was sorted, with no bigger value
+// before it and no smaller one after it.
+func (s *StatsService) selectKth(list []money.Amount, k int) {
+	left, right := 0, len(list)-1
+	for left < right {
+		// median of three keeps sorted and constant lists linear.
+		mid := left + (right-left)/2
+		if list[mid] < list[left] {
+			list[mid], list[left] = list[left], list[mid]
+		}
+		if list[right] < list[left] {
+			list[right], list[left] = list[left], list[right]
+		}
+		if list[right] < list[mid] {
+			list[right], list[mid] = list[mid], list[right]
+		}
+		pivot := list[mid]
+
+		i, j := left, right
+		for i <= j {
+			for list[i] < pivot {
+				i++
+			}
+			for list[j] > pivot {
+				j--
+			}
+			if i <= j {
+				list[i], list[j] = list[j], list[i]
+				i++
+				j--
+			}
+		}
+
+		switch {
+		case k <= j:
+			right = j
+		case k >= i:
+			left = i
+		default:
+			return
+		}
+	}
+}
+
+func (s *StatsService) min(list []money.Amount) money.Amount {
 	if len(list) == 0 {
 		return 0
 	}
@@ -41,7 +221,7 @@ func (s *StatsService) min(list []float64) float64 {
 	return min
 }
 
-func (s *StatsService) max(list []float64) float64 {
+func (s *StatsService) max(list []money.Amount) money.Amount {
 	if len(list) == 0 {
 		return 0
 	}
@@ -3,6 +3,10 @@
 package booking
 
 import (
+	"github.com/xsolrac87/booking/money"
+	"math/rand"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -18,21 +22,21 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      5,
-					SellingRate: 200,
+					SellingRate: money.FromUnits(200),
 					Margin:      25,
 				},
 				{
 					RequestID:   "kayete_PP234",
 					CheckIn:     time.Time{},
 					Nights:      4,
-					SellingRate: 156,
+					SellingRate: money.FromUnits(156),
 					Margin:      22,
 				},
 			},
 			expected: ProfitPerNight{
-				AvgNight: 9.29,
-				MinNight: 8.58,
-				MaxNight: 10.00,
+				AvgNight: money.MustParse("9.29"),
+				MinNight: money.MustParse("8.58"),
+				MaxNight: money.MustParse("10.00"),
 			},
 		},
 		"second use case": {
@@ -41,28 +45,28 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 50,
+					SellingRate: money.FromUnits(50),
 					Margin:      20,
 				},
 				{
 					RequestID:   "kayete_PP234",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 55,
+					SellingRate: money.FromUnits(55),
 					Margin:      22,
 				},
 				{
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 49,
+					SellingRate: money.FromUnits(49),
 					Margin:      21,
 				},
 			},
 			expected: ProfitPerNight{
-				AvgNight: 10.80,
-				MinNight: 10,
-				MaxNight: 12.1,
+				AvgNight: money.MustParse("10.80"),
+				MinNight: money.FromUnits(10),
+				MaxNight: money.MustParse("12.1"),
 			},
 		},
 		"my own use case": {
@@ -71,35 +75,35 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 					RequestID:   "A",
 					CheckIn:     time.Time{},
 					Nights:      7,
-					SellingRate: 244,
+					SellingRate: money.FromUnits(244),
 					Margin:      5,
 				},
 				{
 					RequestID:   "B",
 					CheckIn:     time.Time{},
 					Nights:      5,
-					SellingRate: 100,
+					SellingRate: money.FromUnits(100),
 					Margin:      9,
 				},
 				{
 					RequestID:   "C",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 79,
+					SellingRate: money.FromUnits(79),
 					Margin:      11,
 				},
 				{
 					RequestID:   "D",
 					CheckIn:     time.Time{},
 					Nights:      3,
-					SellingRate: 49,
+					SellingRate: money.FromUnits(49),
 					Margin:      21,
 				},
 			},
 			expected: ProfitPerNight{
-				AvgNight: 3.92,
-				MinNight: 1.74,
-				MaxNight: 8.69,
+				AvgNight: money.MustParse("3.92"),
+				MinNight: money.MustParse("1.74"),
+				MaxNight: money.MustParse("8.69"),
 			},
 		},
 		"use case with invalid booking": {
@@ -108,29 +112,29 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      0,
-					SellingRate: 50,
+					SellingRate: money.FromUnits(50),
 					Margin:      20,
 				},
 				{
 					RequestID:   "kayete_PP234",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 55,
+					SellingRate: money.FromUnits(55),
 					Margin:      22,
 				},
 				{
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      1,
-					SellingRate: 49,
+					SellingRate: money.FromUnits(49),
 					Margin:      21,
 				},
 			},
 
 			expected: ProfitPerNight{
-				AvgNight: 11.20,
-				MinNight: 10.29,
-				MaxNight: 12.1,
+				AvgNight: money.MustParse("11.20"),
+				MinNight: money.MustParse("10.29"),
+				MaxNight: money.MustParse("12.1"),
 			},
 		},
 		"use case with unique invalid booking": {
@@ -139,14 +143,14 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 					RequestID:   "bookata_XY123",
 					CheckIn:     time.Time{},
 					Nights:      0,
-					SellingRate: 50,
+					SellingRate: money.FromUnits(50),
 					Margin:      20,
 				},
 			},
 			expected: ProfitPerNight{
-				AvgNight: 0,
-				MinNight: 0,
-				MaxNight: 0,
+				AvgNight: money.FromUnits(0),
+				MinNight: money.FromUnits(0),
+				MaxNight: money.FromUnits(0),
 			},
 		},
 	}
@@ -155,30 +159,176 @@ func TestStatsService_ProfitPerNight(t *testing.T) {
 		tt := tt
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
-			got := statsService.ProfitPerNight(tt.bookings)
+			got := newStatsService(t).ProfitPerNight(tt.bookings)
 			//todo: Using the != operator to compare two floating-point numbers can lead to inaccuracies ( mantissa )
 			// Instead, we should compare their difference to see if it is less than some small error value.
 			// This can be done with testify testing library and InDelta function. ( https://pkg.go.dev/github.com/stretchr/testify/assert?utm_source=godoc#InDelta )
 			if got != tt.expected {
-				t.Errorf("got: %f, expected: %f", got, tt.expected)
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
 			}
 		})
 	}
 }
 
+func TestStatsService_SummarizeWithDistribution(t *testing.T) {
+	var ten []Booking
+	for margin := int32(1); margin <= 10; margin++ {
+		ten = append(ten, Booking{
+			RequestID:   "acme_AAAA",
+			Nights:      1,
+			SellingRate: money.FromUnits(100),
+			Margin:      margin,
+		})
+	}
+
+	tests := map[string]struct {
+		bookings []Booking
+		opts     []StatsOption
+		expected Summary
+	}{
+		"ten values and an invalid booking": {
+			bookings: append(append([]Booking(nil), ten...), Booking{RequestID: "acme_BBBB", SellingRate: money.FromUnits(100), Margin: 1}),
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.MustParse("5.5"), MinNight: money.FromUnits(1), MaxNight: money.FromUnits(10)},
+				Distribution: &Distribution{
+					MedianNight: money.MustParse("5.5"),
+					P90Night:    money.MustParse("9.1"),
+					P95Night:    money.MustParse("9.55"),
+					P99Night:    money.MustParse("9.91"),
+					StdDevNight: money.MustParse("2.87"),
+					Valid:       10,
+					Skipped:     1,
+				},
+			},
+		},
+		"single value": {
+			bookings: ten[3:4],
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(4), MinNight: money.FromUnits(4), MaxNight: money.FromUnits(4)},
+				Distribution: &Distribution{
+					MedianNight: money.FromUnits(4),
+					P90Night:    money.FromUnits(4),
+					P95Night:    money.FromUnits(4),
+					P99Night:    money.FromUnits(4),
+					Valid:       1,
+				},
+			},
+		},
+		"only invalid bookings": {
+			bookings: []Booking{{RequestID: "acme_BBBB"}},
+			expected: Summary{
+				Distribution: &Distribution{Skipped: 1},
+			},
+		},
+		"duplicates dropped keeping the first": {
+			bookings: ten[:3],
+			opts:     []StatsOption{WithStatsDuplicates(DuplicatesKeepFirst)},
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(1), MinNight: money.FromUnits(1), MaxNight: money.FromUnits(1)},
+				Distribution: &Distribution{
+					MedianNight: money.FromUnits(1),
+					P90Night:    money.FromUnits(1),
+					P95Night:    money.FromUnits(1),
+					P99Night:    money.FromUnits(1),
+					Valid:       1,
+					Skipped:     2,
+				},
+			},
+		},
+		"duplicates dropped keeping the last": {
+			bookings: ten[:3],
+			opts:     []StatsOption{WithStatsDuplicates(DuplicatesKeepLast)},
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(3), MinNight: money.FromUnits(3), MaxNight: money.FromUnits(3)},
+				Distribution: &Distribution{
+					MedianNight: money.FromUnits(3),
+					P90Night:    money.FromUnits(3),
+					P95Night:    money.FromUnits(3),
+					P99Night:    money.FromUnits(3),
+					Valid:       1,
+					Skipped:     2,
+				},
+			},
+		},
+		"unread bookings": {
+			bookings: ten[3:4],
+			opts:     []StatsOption{withUnread(2)},
+			expected: Summary{
+				ProfitPerNight: ProfitPerNight{AvgNight: money.FromUnits(4), MinNight: money.FromUnits(4), MaxNight: money.FromUnits(4)},
+				Distribution: &Distribution{
+					MedianNight: money.FromUnits(4),
+					P90Night:    money.FromUnits(4),
+					P95Night:    money.FromUnits(4),
+					P99Night:    money.FromUnits(4),
+					Valid:       1,
+					Skipped:     2,
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newStatsService(t).Summarize(tt.bookings, append(tt.opts, WithDistribution())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v %+v, expected: %+v %+v", got.ProfitPerNight, got.Distribution, tt.expected.ProfitPerNight, tt.expected.Distribution)
+			}
+		})
+	}
+}
+
+// TestStatsService_DistributionOracle checks the percentiles selected in linear time against a sorted list.
+func TestStatsService_DistributionOracle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(23))
+	for run := 0; run < 300; run++ {
+		list := make([]money.Amount, 1+rnd.Intn(200))
+		for i := range list {
+			// few distinct values so that ties are common.
+			list[i] = money.FromMinor(int64(rnd.Intn(1 + rnd.Intn(5000))))
+		}
+
+		sorted := append([]money.Amount(nil), list...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i] < sorted[j]
+		})
+		percentile := func(p int64) money.Amount {
+			rank := p * int64(len(sorted)-1)
+			low, high := sorted[rank/100], sorted[(rank+99)/100]
+			return low + (high-low).MulDiv(rank%100, 100, statsRounding)
+		}
+
+		var sum money.Amount
+		for _, v := range list {
+			sum += v
+		}
+
+		got := newStatsService(t).distribution(list, sum, 0)
+		expected := NewDistribution(percentile(50), percentile(90), percentile(95), percentile(99), got.StdDevNight, len(list), 0)
+		if got != expected {
+			t.Fatalf("run %d: got: %+v, expected: %+v", run, got, expected)
+		}
+	}
+}
+
 const n = 1_000_000
 
 var statsGlobal ProfitPerNight
 
 func BenchmarkProfitPerNight(b *testing.B) {
 	var p ProfitPerNight
+	service := newStatsService(b)
 	bookings := make([]Booking, 0, n)
 	for i := 0; i < n; i++ {
 		bookings = append(bookings, Booking{
 			RequestID:   "kayete_PP234",
 			CheckIn:     time.Time{},
 			Nights:      4,
-			SellingRate: 156,
+			SellingRate: money.FromUnits(156),
 			Margin:      22,
 		})
 	}
@@ -186,7 +336,38 @@ func BenchmarkProfitPerNight(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		p = statsService.ProfitPerNight(bookings)
+		p = service.ProfitPerNight(bookings)
 	}
 	statsGlobal = p
 }
+
+var summaryGlobal Summary
+
+func BenchmarkSummarizeWithDistribution(b *testing.B) {
+	var (
+		p        Summary
+		err      error
+		rnd      = rand.New(rand.NewSource(1))
+		service  = newStatsService(b)
+		bookings = make([]Booking, 0, n)
+	)
+	for i := 0; i < n; i++ {
+		bookings = append(bookings, Booking{
+			RequestID:   "kayete_PP234",
+			CheckIn:     time.Time{},
+			Nights:      1 + rnd.Int31n(14),
+			SellingRate: money.FromMinor(1 + rnd.Int63n(100000)),
+			Margin:      1 + rnd.Int31n(30),
+		})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err = service.Summarize(bookings, WithDistribution())
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	summaryGlobal = p
+}
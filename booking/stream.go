@@ -0,0 +1,172 @@
+package booking
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	// maxLineSize is the longest line of an NDJSON payload, far above any booking.
+	maxLineSize = 1 << 20
+	// maxBookings caps the bookings of a payload kept in memory, for the endpoints that need all of them at once.
+	maxBookings = 5_000_000
+)
+
+var (
+	errLineTooLong     = fmt.Errorf("ndjson lines should not be longer than %d bytes", maxLineSize)
+	errTooManyBookings = fmt.Errorf("payload should not have more than %d bookings", maxBookings)
+	errStreamedOption  = errors.New("option is not available for streamed payloads")
+)
+
+// BookingSource gives the bookings of a payload one at a time, so they do not need to be held in memory at once.
+type BookingSource interface {
+	// Next returns the next booking, and false once there are no more.
+	Next() (Booking, bool, error)
+}
+
+// ndjsonSource reads the bookings of an NDJSON payload, one JSON object per line, under a validation mode. Blank
+// lines are skipped, and issues are reported at the index of their line, from 0.
+type ndjsonSource struct {
+	body      *bodyReader
+	scanner   *bufio.Scanner
+	collector *collector
+	line      int
+	read      int
+}
+
+func newNDJSONSource(r io.Reader, mode ValidationMode) *ndjsonSource {
+	body := &bodyReader{r: r}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &ndjsonSource{body: body, scanner: scanner, collector: newCollector(mode, 0)}
+}
+
+// bodyReader keeps the first error reading r, as the scanner still gives the line cut short by it before failing.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// Next returns the next booking that can be read. Once the payload is over, it returns the issues found under
+// strict validation.
+func (s *ndjsonSource) Next() (Booking, bool, error) {
+	for s.scanner.Scan() {
+		if s.body.err != nil {
+			return Booking{}, false, readError(s.body.err)
+		}
+		index := s.line
+		s.line++
+
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var b Booking
+		found := b.decode(line)
+		ok, err := s.collector.check(index, b, found)
+		if err != nil {
+			return Booking{}, false, fmt.Errorf("line %d: %w", index+1, err)
+		}
+		if ok {
+			s.read++
+			return b, true, nil
+		}
+	}
+
+	err := s.scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return Booking{}, false, fmt.Errorf("%w: line %d", errLineTooLong, s.line+1)
+	}
+	if err != nil {
+		return Booking{}, false, readError(err)
+	}
+	return Booking{}, false, s.collector.err()
+}
+
+// collect returns every booking of src, up to maxBookings.
+func collect(src BookingSource) ([]Booking, error) {
+	var bookings []Booking
+	for {
+		b, ok, err := src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return bookings, nil
+		}
+		if len(bookings) == maxBookings {
+			return nil, errTooManyBookings
+		}
+		bookings = append(bookings, b)
+	}
+}
+
+// SummarizeStream returns the same stats as Summarize for the bookings of src, read one at a time in constant memory
+// but for the groups. The distribution and the duplicate policy need every booking at once, so they are not available.
+func (s *StatsService) SummarizeStream(src BookingSource, opts ...StatsOption) (Summary, error) {
+	options, err := s.options(opts)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer logOperation(options.loggerOrDefault(), "summarize_stream", time.Now())
+	if options.distribution {
+		return Summary{}, fmt.Errorf("%w: detailed", errStreamedOption)
+	}
+	if options.duplicates != "" {
+		return Summary{}, fmt.Errorf("%w: duplicates", errStreamedOption)
+	}
+
+	var (
+		total  accumulator
+		groups = newGroups(options.groupBy)
+	)
+	for {
+		booking, ok, err := src.Next()
+		if err != nil {
+			return Summary{}, err
+		}
+		if !ok {
+			break
+		}
+
+		booking, ok = options.horizon.clip(booking)
+		if !ok || !booking.valid() {
+			continue
+		}
+		booking, err = options.conversion.convert(booking)
+		if err != nil {
+			return Summary{}, err
+		}
+
+		p := profitPerNight(booking.SellingRate, booking.Margin, booking.Nights)
+		err = total.add(p)
+		if err != nil {
+			return Summary{}, err
+		}
+		err = groups.add(booking, p)
+		if err != nil {
+			return Summary{}, err
+		}
+	}
+
+	return Summary{
+		ProfitPerNight: total.profitPerNight(),
+		Groups:         groups.list(),
+		Reporting:      options.conversion.reporting(),
+	}, nil
+}
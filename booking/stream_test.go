@@ -0,0 +1,154 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONSource_Next(t *testing.T) {
+	payload := `{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10}
+
+{"request_id": "B", "check_in": "2023-01-01", "nights": "2", "selling_rate": 100, "margin": 10}
+{"request_id": "C", "check_in": "2023-01-01", "nights": 0, "selling_rate": 100, "margin": 10}
+not json
+`
+
+	tests := map[string]struct {
+		payload        string
+		mode           ValidationMode
+		expected       []string
+		expectedIssues []Issue
+		expectedErr    error
+	}{
+		"first issue by default": {
+			payload:     payload,
+			expectedErr: errWrongType,
+		},
+		"lenient": {
+			payload:  payload,
+			mode:     ValidationLenient,
+			expected: []string{"A", "C"},
+		},
+		"strict": {
+			payload: payload,
+			mode:    ValidationStrict,
+			expectedIssues: []Issue{
+				{Index: 2, Field: "nights", Reason: ReasonWrongType},
+				{Index: 3, Field: "nights", Reason: ReasonNotPositive},
+				{Index: 4, Reason: ReasonWrongType},
+			},
+			expectedErr: errInvalidBookings,
+		},
+		"line too long": {
+			payload:     `{"request_id": "` + strings.Repeat("A", maxLineSize) + `"}`,
+			mode:        ValidationLenient,
+			expectedErr: errLineTooLong,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			src := newNDJSONSource(strings.NewReader(tt.payload), tt.mode)
+			bookings, err := collect(src)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+
+			var validation *ValidationError
+			if errors.As(err, &validation) {
+				for i := range validation.Issues {
+					validation.Issues[i].Message = ""
+				}
+				if !reflect.DeepEqual(validation.Issues, tt.expectedIssues) {
+					t.Errorf("got issues: %+v, expected: %+v", validation.Issues, tt.expectedIssues)
+				}
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, b := range bookings {
+				got = append(got, b.RequestID)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
+			}
+			if src.read != len(tt.expected) {
+				t.Errorf("got %d bookings read, expected %d", src.read, len(tt.expected))
+			}
+		})
+	}
+}
+
+// sliceSource gives the bookings of a slice, one at a time.
+type sliceSource []Booking
+
+func (s *sliceSource) Next() (Booking, bool, error) {
+	if len(*s) == 0 {
+		return Booking{}, false, nil
+	}
+	b := (*s)[0]
+	*s = (*s)[1:]
+	return b, true, nil
+}
+
+func TestStatsService_SummarizeStream(t *testing.T) {
+	bookings := randomBookings(rand.New(rand.NewSource(7)), 500)
+
+	tests := map[string][]StatsOption{
+		"plain":    nil,
+		"grouped":  {WithGroupBy(GroupByMonth, GroupByLengthOfStay)},
+		"horizon":  {WithStatsHorizon(parse("2023-01-10"), parse("2023-01-20"), HorizonProrate)},
+		"currency": {WithStatsRates(testRates(t)), WithStatsCurrency("USD")},
+	}
+
+	for name, opts := range tests {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			expected, err := newStatsService(t).Summarize(bookings, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			src := sliceSource(bookings)
+			got, err := newStatsService(t).SummarizeStream(&src, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("got: %+v, expected: %+v", got, expected)
+			}
+		})
+	}
+
+	for _, opt := range []StatsOption{WithDistribution(), WithStatsDuplicates(DuplicatesKeepFirst)} {
+		src := sliceSource(bookings)
+		_, err := newStatsService(t).SummarizeStream(&src, opt)
+		if !errors.Is(err, errStreamedOption) {
+			t.Errorf("got error: %v, expected: %v", err, errStreamedOption)
+		}
+	}
+}
+
+func BenchmarkStatsService_SummarizeStream(b *testing.B) {
+	line := `{"request_id": "A", "check_in": "2023-01-01", "nights": 3, "selling_rate": 150, "margin": 12}`
+	payload := strings.Repeat(line+"\n", 10_000)
+	service := newStatsService(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := service.SummarizeStream(newNDJSONSource(strings.NewReader(payload), ""))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
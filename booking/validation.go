@@ -0,0 +1,164 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ValidationMode sets what to do with the bookings of a payload that cannot be taken into account.
+type ValidationMode string
+
+const (
+	// ValidationLenient skips the bookings that cannot be read.
+	ValidationLenient ValidationMode = "lenient"
+	// ValidationStrict rejects the payload, listing every issue of every booking.
+	ValidationStrict ValidationMode = "strict"
+)
+
+// Reason tells why a property of a booking cannot be taken into account.
+type Reason string
+
+const (
+	ReasonMissing     Reason = "missing"
+	ReasonWrongType   Reason = "wrong_type"
+	ReasonBadDate     Reason = "bad_date"
+	ReasonBadAmount   Reason = "bad_amount"
+	ReasonBadCurrency Reason = "bad_currency"
+	ReasonNotPositive Reason = "not_positive"
+	ReasonTooHigh     Reason = "too_high"
+)
+
+var (
+	errValidationMode  = errors.New("validation should be lenient or strict")
+	errInvalidBookings = errors.New("payload contains invalid bookings")
+)
+
+// fieldIssue is a property of a booking that cannot be taken into account, with the error it is reported with.
+type fieldIssue struct {
+	field  string
+	reason Reason
+	err    error
+}
+
+// Issue is a property of the booking at Index of the payload that cannot be taken into account. Field is empty when
+// the booking itself is not an object.
+type Issue struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field,omitempty"`
+	Reason  Reason `json:"reason"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every issue of the bookings of a payload validated strictly.
+type ValidationError struct {
+	Issues []Issue `json:"issues"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %d issues", errInvalidBookings, len(e.Issues))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return errInvalidBookings
+}
+
+// decodeBookings returns the bookings of the JSON array data under mode, up to maxBookings, and how many were left out
+// as they could not be read.
+func decodeBookings(data []byte, mode ValidationMode) ([]Booking, int, error) {
+	var items []json.RawMessage
+	err := json.Unmarshal(data, &items)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(items) > maxBookings {
+		return nil, 0, errTooManyBookings
+	}
+
+	c := newCollector(mode, len(items))
+	for i, item := range items {
+		var b Booking
+		found := b.decode(item)
+		err = c.add(i, b, found)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return c.result()
+}
+
+// collector gathers the bookings of a payload, one at a time, under a validation mode. With no mode, the first
+// booking that cannot be read fails the payload; lenient skips them, leaving the ones breaking a rule to be skipped
+// as usual; strict lists the issues of both in a *ValidationError.
+type collector struct {
+	mode     ValidationMode
+	bookings []Booking
+	issues   []Issue
+	// unread counts the bookings left out as they could not be read, or broke a rule under strict validation.
+	unread int
+}
+
+func newCollector(mode ValidationMode, size int) *collector {
+	return &collector{mode: mode, bookings: make([]Booking, 0, size)}
+}
+
+// add keeps the booking b at index, decoded with the issues found, when it is to be taken into account.
+func (c *collector) add(index int, b Booking, found []fieldIssue) error {
+	ok, err := c.check(index, b, found)
+	if ok {
+		c.bookings = append(c.bookings, b)
+	}
+	return err
+}
+
+// check tells whether the booking b at index, decoded with the issues found, is to be taken into account, noting
+// its issues otherwise.
+func (c *collector) check(index int, b Booking, found []fieldIssue) (bool, error) {
+	if c.mode == ValidationStrict {
+		found = appendViolations(found, b.violations())
+	}
+	if len(found) == 0 {
+		return true, nil
+	}
+	if c.mode == "" {
+		return false, found[0].err
+	}
+
+	c.unread++
+	for _, issue := range found {
+		c.issues = append(c.issues, Issue{Index: index, Field: issue.field, Reason: issue.reason, Message: issue.err.Error()})
+	}
+	return false, nil
+}
+
+// err returns the issues found under strict validation, if any.
+func (c *collector) err() error {
+	if c.mode == ValidationStrict && len(c.issues) > 0 {
+		return &ValidationError{Issues: c.issues}
+	}
+	return nil
+}
+
+func (c *collector) result() ([]Booking, int, error) {
+	if err := c.err(); err != nil {
+		return nil, 0, err
+	}
+	return c.bookings, c.unread, nil
+}
+
+// appendViolations appends to issues the violations of the fields that could be read, as the others hold no value.
+func appendViolations(issues, violations []fieldIssue) []fieldIssue {
+	for _, v := range violations {
+		read := true
+		for _, issue := range issues {
+			if issue.field == v.field || issue.field == "" {
+				read = false
+				break
+			}
+		}
+		if read {
+			issues = append(issues, v)
+		}
+	}
+	return issues
+}
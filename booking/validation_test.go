@@ -0,0 +1,90 @@
+//go:build unit
+
+package booking
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeBookings(t *testing.T) {
+	payload := []byte(`[
+		{"request_id": "A", "check_in": "2023-01-01", "nights": 2, "selling_rate": 100, "margin": 10},
+		{"request_id": 7, "check_in": "2023-02-30", "nights": 2, "selling_rate": 100},
+		{"request_id": "C", "check_in": "2023-01-01", "nights": 0, "selling_rate": -1, "margin": 10},
+		{"request_id": "D", "check_in": "2023-01-01", "nights": "2", "selling_rate": 100, "margin": 0},
+		"E"
+	]`)
+	valid := []Booking{
+		{RequestID: "A", CheckIn: parse("2023-01-01"), Nights: 2, SellingRate: 10000, Margin: 10},
+		{RequestID: "C", CheckIn: parse("2023-01-01"), Nights: 0, SellingRate: -100, Margin: 10},
+	}
+
+	tests := map[string]struct {
+		mode           ValidationMode
+		expected       []Booking
+		expectedUnread int
+		expectedIssues []Issue
+	}{
+		"lenient skips the bookings that cannot be read": {
+			mode:           ValidationLenient,
+			expected:       valid,
+			expectedUnread: 3,
+		},
+		"strict lists every issue": {
+			mode: ValidationStrict,
+			expectedIssues: []Issue{
+				{Index: 1, Field: "request_id", Reason: ReasonWrongType},
+				{Index: 1, Field: "check_in", Reason: ReasonBadDate},
+				{Index: 1, Field: "margin", Reason: ReasonMissing},
+				{Index: 2, Field: "nights", Reason: ReasonNotPositive},
+				{Index: 2, Field: "selling_rate", Reason: ReasonNotPositive},
+				{Index: 3, Field: "nights", Reason: ReasonWrongType},
+				{Index: 3, Field: "margin", Reason: ReasonNotPositive},
+				{Index: 4, Reason: ReasonWrongType},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, unread, err := decodeBookings(payload, tt.mode)
+
+			var validation *ValidationError
+			if errors.As(err, &validation) {
+				if !errors.Is(err, errInvalidBookings) {
+					t.Errorf("got error: %v, expected: %v", err, errInvalidBookings)
+				}
+				issues := make([]Issue, 0, len(validation.Issues))
+				for _, issue := range validation.Issues {
+					if issue.Message == "" {
+						t.Errorf("issue %+v has no message", issue)
+					}
+					issue.Message = ""
+					issues = append(issues, issue)
+				}
+				if !reflect.DeepEqual(issues, tt.expectedIssues) {
+					t.Errorf("got issues: %+v, expected: %+v", issues, tt.expectedIssues)
+				}
+				return
+			}
+			if err != nil || tt.expectedIssues != nil {
+				t.Fatalf("got error: %v, expected issues: %+v", err, tt.expectedIssues)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got: %+v, expected: %+v", got, tt.expected)
+			}
+			if unread != tt.expectedUnread {
+				t.Errorf("got %d unread, expected %d", unread, tt.expectedUnread)
+			}
+		})
+	}
+
+	_, _, err := decodeBookings([]byte(`{"request_id": "A"}`), ValidationStrict)
+	if err == nil {
+		t.Error("expected an error decoding a payload that is not an array")
+	}
+}
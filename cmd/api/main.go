@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/xsolrac87/booking/api"
-	"log"
+	"github.com/xsolrac87/booking/fx"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strconv"
@@ -13,22 +14,62 @@ import (
 )
 
 func main() {
+	// Logging
+	logger, err := newLogger()
+	if err != nil {
+		slog.Error("failed to configure logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// HTTP Server
 	port, _ := strconv.Atoi(os.Getenv("SERVER_PORT"))
 	timeout, _ := strconv.Atoi(os.Getenv("SERVER_TIMEOUT"))
-	httpServer, err := api.NewHTTPServer(
-		"",
+	opts := []api.Option{
 		api.WithPort(port),
-		api.WithTimeout(time.Duration(timeout)*time.Second),
-	)
+		api.WithTimeout(time.Duration(timeout) * time.Second),
+		api.WithLogger(logger),
+	}
+	if v := os.Getenv("SERVER_GZIP_LEVEL"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil {
+			fatal(logger, "invalid SERVER_GZIP_LEVEL", err)
+		}
+		opts = append(opts, api.WithGzip(level))
+	}
+	if v := os.Getenv("SERVER_MAX_BODY_SIZE"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			fatal(logger, "invalid SERVER_MAX_BODY_SIZE", err)
+		}
+		opts = append(opts, api.WithMaxBodySize(size))
+	}
+
+	// Booking
+	if v, ok := os.LookupEnv("BOOKING_BUFFER_NIGHTS"); ok {
+		bufferNights, err := strconv.Atoi(v)
+		if err != nil {
+			fatal(logger, "invalid BOOKING_BUFFER_NIGHTS", err)
+		}
+		opts = append(opts, api.WithBufferNights(bufferNights))
+	}
+	if path := os.Getenv("BOOKING_RATES_FILE"); path != "" {
+		rates, err := fx.ReadStaticRates(path)
+		if err != nil {
+			fatal(logger, "failed to read the exchange rates", err)
+		}
+		opts = append(opts, api.WithRates(rates))
+	}
+
+	httpServer, err := api.NewHTTPServer("", opts...)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "failed to build the HTTP server", err)
 	}
 
 	// APi
 	serverAPI, err := api.New(httpServer)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "failed to build the API", err)
 	}
 
 	// Context
@@ -37,7 +78,7 @@ func main() {
 
 	// Run server
 	if err := serverAPI.RunAPI(ctx); err != nil {
-		log.Fatal(err)
+		fatal(logger, "API stopped", err)
 	}
 }
 
@@ -45,6 +86,34 @@ func applicationEnd(cf context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL)
 	sig := <-sigChan
-	fmt.Printf("context cancel form signal %v \n", sig)
+	slog.Info("context cancel from signal", "signal", sig.String())
 	cf()
 }
+
+// newLogger returns the logger of the service, writing to stderr as LOG_FORMAT tells, text ( default ) or json, the
+// lines of LOG_LEVEL and above, debug, info ( default ), warn or error.
+func newLogger() (*slog.Logger, error) {
+	var level slog.Level
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		err := level.UnmarshalText([]byte(v))
+		if err != nil {
+			return nil, err
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT should be text or json, got %q", format)
+	}
+}
+
+// fatal logs err and exits.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,123 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xsolrac87/booking/money"
+	"math/big"
+)
+
+// rateScale is how many parts every rate is kept in, so rates up to 6 decimals convert amounts exactly.
+const rateScale = 1_000_000
+
+var (
+	ErrCurrency        = errors.New("currency should be a 3-letter ISO 4217 code")
+	ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+	errVersion  = errors.New("rate table should have a version")
+	errRate     = errors.New("rate should be a positive number with no more than 6 decimals")
+	errBaseRate = errors.New("rate of the base currency should be 1")
+)
+
+// RateProvider gives the exchange rates to convert amounts with. A provider may return a newer table on every call,
+// so all the amounts of a request should be converted with the same table.
+type RateProvider interface {
+	Rates() (Table, error)
+}
+
+// Table holds how many units of every currency a unit of the base currency buys, under a version naming the table.
+type Table struct {
+	version string
+	base    string
+	rates   map[string]int64
+}
+
+// NewTable returns the table of the given rates, decimal numbers of units per unit of base, which is added with a
+// rate of 1 when missing.
+func NewTable(version, base string, rates map[string]string) (Table, error) {
+	if version == "" {
+		return Table{}, errVersion
+	}
+	if !ValidCurrency(base) {
+		return Table{}, fmt.Errorf("%w: %s", ErrCurrency, base)
+	}
+
+	t := Table{
+		version: version,
+		base:    base,
+		rates:   map[string]int64{base: rateScale},
+	}
+	for currency, rate := range rates {
+		if !ValidCurrency(currency) {
+			return Table{}, fmt.Errorf("%w: %s", ErrCurrency, currency)
+		}
+		r, err := parseRate(rate)
+		if err != nil {
+			return Table{}, fmt.Errorf("%w: %s %s", err, currency, rate)
+		}
+		if currency == base && r != rateScale {
+			return Table{}, fmt.Errorf("%w: %s %s", errBaseRate, currency, rate)
+		}
+		t.rates[currency] = r
+	}
+	return t, nil
+}
+
+// Version names the table, so responses can tell which rates they were computed with.
+func (t Table) Version() string {
+	return t.version
+}
+
+// Base returns the currency every rate is relative to.
+func (t Table) Base() string {
+	return t.base
+}
+
+// Has tells whether amounts can be converted from and to currency.
+func (t Table) Has(currency string) bool {
+	_, ok := t.rates[currency]
+	return ok
+}
+
+// Convert returns the amount in from currency converted to currency to, rounded to the minor unit with mode.
+func (t Table) Convert(a money.Amount, from, to string, mode money.RoundingMode) (money.Amount, error) {
+	fromRate, ok := t.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+	if from == to {
+		return a, nil
+	}
+	return a.CheckedMulDiv(toRate, fromRate, mode)
+}
+
+// ValidCurrency tells whether currency looks like an ISO 4217 code: three upper case letters.
+func ValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRate returns the decimal rate s in millionths.
+func parseRate(s string) (int64, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return 0, errRate
+	}
+
+	r.Mul(r, big.NewRat(rateScale, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, errRate
+	}
+	return r.Num().Int64(), nil
+}
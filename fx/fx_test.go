@@ -0,0 +1,148 @@
+//go:build unit
+
+package fx
+
+import (
+	"errors"
+	"github.com/xsolrac87/booking/money"
+	"strings"
+	"testing"
+)
+
+func TestNewTable(t *testing.T) {
+	tests := map[string]struct {
+		version     string
+		base        string
+		rates       map[string]string
+		expectedErr error
+	}{
+		"valid":              {version: "v1", base: "EUR", rates: map[string]string{"GBP": "0.85", "EUR": "1.000"}},
+		"missing version":    {base: "EUR", expectedErr: errVersion},
+		"invalid base":       {version: "v1", base: "eur", expectedErr: ErrCurrency},
+		"invalid currency":   {version: "v1", base: "EUR", rates: map[string]string{"POUND": "0.85"}, expectedErr: ErrCurrency},
+		"zero rate":          {version: "v1", base: "EUR", rates: map[string]string{"GBP": "0"}, expectedErr: errRate},
+		"negative rate":      {version: "v1", base: "EUR", rates: map[string]string{"GBP": "-0.85"}, expectedErr: errRate},
+		"too many decimals":  {version: "v1", base: "EUR", rates: map[string]string{"GBP": "0.8512345"}, expectedErr: errRate},
+		"not a number":       {version: "v1", base: "EUR", rates: map[string]string{"GBP": "abc"}, expectedErr: errRate},
+		"base rate is not 1": {version: "v1", base: "EUR", rates: map[string]string{"EUR": "1.1"}, expectedErr: errBaseRate},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewTable(tt.version, tt.base, tt.rates)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestTable_Convert(t *testing.T) {
+	table, err := NewTable("v1", "EUR", map[string]string{"GBP": "0.8512", "USD": "1.0801"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		amount      money.Amount
+		from, to    string
+		expected    money.Amount
+		expectedErr error
+	}{
+		"same currency":   {amount: money.MustParse("199.99"), from: "GBP", to: "GBP", expected: money.MustParse("199.99")},
+		"from base":       {amount: money.FromUnits(100), from: "EUR", to: "GBP", expected: money.MustParse("85.12")},
+		"to base":         {amount: money.MustParse("85.12"), from: "GBP", to: "EUR", expected: money.FromUnits(100)},
+		"across the base": {amount: money.FromUnits(100), from: "GBP", to: "USD", expected: money.MustParse("126.89")},
+		"rounded":         {amount: money.MustParse("0.01"), from: "EUR", to: "GBP", expected: money.MustParse("0.01")},
+		"unknown from":    {amount: money.FromUnits(100), from: "JPY", to: "EUR", expectedErr: ErrUnknownCurrency},
+		"unknown to":      {amount: money.FromUnits(100), from: "EUR", to: "JPY", expectedErr: ErrUnknownCurrency},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := table.Convert(tt.amount, tt.from, tt.to, money.HalfEven)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("got: %v, expected: %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadStaticRates(t *testing.T) {
+	rates, err := ReadStaticRates("testdata/rates.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := rates.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Version() != "2024-06-01" || table.Base() != "EUR" {
+		t.Errorf("got version: %s, base: %s", table.Version(), table.Base())
+	}
+	for _, currency := range []string{"EUR", "GBP", "USD"} {
+		if !table.Has(currency) {
+			t.Errorf("missing rate for %s", currency)
+		}
+	}
+
+	_, err = ReadStaticRates("testdata/missing.json")
+	if err == nil {
+		t.Error("expected an error reading a missing file")
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	tests := map[string]struct {
+		payload     string
+		expectedErr error
+	}{
+		"exact rates":   {payload: `{"version": "v1", "base": "EUR", "rates": {"GBP": 0.851234}}`},
+		"rates as text": {payload: `{"version": "v1", "base": "EUR", "rates": {"GBP": "0.85"}}`},
+		"invalid rate":  {payload: `{"version": "v1", "base": "EUR", "rates": {"GBP": 1e-9}}`, expectedErr: errRate},
+		"no version":    {payload: `{"base": "EUR", "rates": {"GBP": 0.85}}`, expectedErr: errVersion},
+		"invalid base":  {payload: `{"version": "v1", "rates": {"GBP": 0.85}}`, expectedErr: ErrCurrency},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ReadTable(strings.NewReader(tt.payload))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	_, err := ReadTable(strings.NewReader(`{"version": "v1",`))
+	if err == nil {
+		t.Error("expected an error decoding a truncated table")
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	tests := map[string]bool{
+		"EUR":  true,
+		"GBP":  true,
+		"eur":  false,
+		"EU":   false,
+		"EURO": false,
+		"E1R":  false,
+		"":     false,
+	}
+
+	for currency, expected := range tests {
+		if got := ValidCurrency(currency); got != expected {
+			t.Errorf("%q got: %t, expected: %t", currency, got, expected)
+		}
+	}
+}
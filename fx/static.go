@@ -0,0 +1,58 @@
+package fx
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// StaticRates is a RateProvider always giving the same table, like one read once from a file.
+type StaticRates struct {
+	table Table
+}
+
+// NewStaticRates returns a provider always giving t.
+func NewStaticRates(t Table) *StaticRates {
+	return &StaticRates{table: t}
+}
+
+// ReadStaticRates returns a provider giving the table in the JSON file at path, such as
+// {"version": "2024-06-01", "base": "EUR", "rates": {"GBP": 0.85, "USD": 1.08}}.
+func ReadStaticRates(path string) (*StaticRates, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := ReadTable(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticRates(t), nil
+}
+
+// ReadTable decodes a JSON table, keeping the rates as they were written so they are read exactly.
+func ReadTable(r io.Reader) (Table, error) {
+	var payload struct {
+		Version string                 `json:"version"`
+		Base    string                 `json:"base"`
+		Rates   map[string]json.Number `json:"rates"`
+	}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	err := d.Decode(&payload)
+	if err != nil {
+		return Table{}, err
+	}
+
+	rates := make(map[string]string, len(payload.Rates))
+	for currency, rate := range payload.Rates {
+		rates[currency] = rate.String()
+	}
+	return NewTable(payload.Version, payload.Base, rates)
+}
+
+func (s *StaticRates) Rates() (Table, error) {
+	return s.table, nil
+}
@@ -1,3 +1,3 @@
 module github.com/xsolrac87/booking
 
-go 1.19
+go 1.21
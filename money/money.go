@@ -0,0 +1,273 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Scale is how many minor units, cents, make a unit.
+const Scale = 100
+
+// ErrRange is returned by the checked operations whose result does not fit in an amount.
+var ErrRange = errors.New("amount is out of range")
+
+var (
+	errSyntax    = errors.New("amount should be a decimal number")
+	errPrecision = errors.New("amount should not have more than 2 decimals")
+	errDivision  = errors.New("division by zero")
+)
+
+// Amount is an amount of money in minor units, so adding and comparing amounts is exact. It's read from and written
+// to JSON as a decimal number of units, like 199.99.
+type Amount int64
+
+// RoundingMode tells how an amount that falls between two minor units is rounded.
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest minor unit, ties to the even one, so rounding errors cancel out over many amounts.
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest minor unit, ties away from zero.
+	HalfUp
+	// Down rounds towards zero.
+	Down
+	// Up rounds away from zero.
+	Up
+	// Floor rounds towards negative infinity.
+	Floor
+	// Ceiling rounds towards positive infinity.
+	Ceiling
+)
+
+// FromMinor returns the amount of the given minor units.
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// FromUnits returns the amount of the given whole units.
+func FromUnits(units int64) Amount {
+	return Amount(units * Scale)
+}
+
+// FromFloat returns the amount of f units, rounded to the minor unit with mode.
+func FromFloat(f float64, mode RoundingMode) Amount {
+	minor := f * Scale
+	whole := math.Trunc(minor)
+	return Amount(whole).adjust(minor-whole, mode)
+}
+
+// Parse reads a decimal number of units, like 199.99, -3 or 1.5e2, with no more than 2 decimals.
+func Parse(s string) (Amount, error) {
+	mantissa, exponent, found := strings.Cut(strings.ToLower(s), "e")
+	exp := 0
+	if found {
+		var err error
+		exp, err = strconv.Atoi(exponent)
+		if err != nil {
+			return 0, errSyntax
+		}
+	}
+
+	negative := strings.HasPrefix(mantissa, "-")
+	if negative || strings.HasPrefix(mantissa, "+") {
+		mantissa = mantissa[1:]
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := integer + fraction
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, errSyntax
+	}
+	if strings.Trim(digits, "0") == "" {
+		return 0, nil
+	}
+
+	// digits is the amount in 10^shift minor units.
+	shift := exp - len(fraction) + 2
+	for ; shift < 0; shift++ {
+		if !strings.HasSuffix(digits, "0") {
+			return 0, errPrecision
+		}
+		digits = digits[:len(digits)-1]
+	}
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return 0, nil
+	}
+	if len(digits)+shift > 19 {
+		return 0, ErrRange
+	}
+	digits += strings.Repeat("0", shift)
+
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, ErrRange
+	}
+	if negative {
+		minor = -minor
+	}
+	return Amount(minor), nil
+}
+
+// MustParse is like Parse but panics if s is not a valid amount. It simplifies amounts known at compile time.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic("money: Parse(" + strconv.Quote(s) + "): " + err.Error())
+	}
+	return a
+}
+
+// Minor returns the amount in minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float64 returns the amount in units, as the closest float.
+func (a Amount) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// MulDiv returns a * num / den rounded to the minor unit with mode, computing the product on 128 bits so that it
+// cannot overflow. It panics if den is zero or the result does not fit in an amount.
+func (a Amount) MulDiv(num, den int64, mode RoundingMode) Amount {
+	result, err := a.CheckedMulDiv(num, den, mode)
+	if err != nil {
+		panic("money: " + err.Error())
+	}
+	return result
+}
+
+// CheckedAdd returns a + b, or ErrRange when the sum does not fit in an amount.
+func (a Amount) CheckedAdd(b Amount) (Amount, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, ErrRange
+	}
+	return sum, nil
+}
+
+// CheckedMulDiv is like MulDiv but returns an error instead of panicking, for factors not known to be safe.
+func (a Amount) CheckedMulDiv(num, den int64, mode RoundingMode) (Amount, error) {
+	if den == 0 {
+		return 0, errDivision
+	}
+
+	negative := (a < 0) != (num < 0) != (den < 0)
+	hi, lo := bits.Mul64(abs(int64(a)), abs(num))
+	d := abs(den)
+	if hi >= d {
+		return 0, ErrRange
+	}
+	q, r := bits.Div64(hi, lo, d)
+	if q > math.MaxInt64 {
+		return 0, ErrRange
+	}
+
+	result := Amount(q)
+	if negative {
+		result = -result
+	}
+
+	// rest only tells whether the remainder is below, at or above half a minor unit, with the sign of the result.
+	var rest float64
+	switch {
+	case r == 0:
+	case r < d-r:
+		rest = 0.25
+	case r == d-r:
+		rest = 0.5
+	default:
+		rest = 0.75
+	}
+	if negative {
+		rest = -rest
+	}
+	return result.adjust(rest, mode), nil
+}
+
+// adjust rounds a plus a rest strictly between -1 and 1 minor units with mode.
+func (a Amount) adjust(rest float64, mode RoundingMode) Amount {
+	if rest == 0 {
+		return a
+	}
+
+	var away bool
+	switch mode {
+	case HalfEven:
+		away = math.Abs(rest) > 0.5 || math.Abs(rest) == 0.5 && a%2 != 0
+	case HalfUp:
+		away = math.Abs(rest) >= 0.5
+	case Down:
+	case Up:
+		away = true
+	case Floor:
+		away = rest < 0
+	case Ceiling:
+		away = rest > 0
+	}
+
+	switch {
+	case away && rest > 0:
+		return a + 1
+	case away && rest < 0:
+		return a - 1
+	}
+	return a
+}
+
+// String returns the amount as a decimal number of units, with no trailing zeros after the point.
+func (a Amount) String() string {
+	var (
+		units = int64(a) / Scale
+		cents = abs(int64(a) % Scale)
+		sign  string
+	)
+	if a < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	s := sign + strconv.FormatUint(uint64(units), 10)
+	switch {
+	case cents == 0:
+		return s
+	case cents%10 == 0:
+		return s + "." + strconv.FormatUint(cents/10, 10)
+	case cents < 10:
+		return s + ".0" + strconv.FormatUint(cents, 10)
+	default:
+		return s + "." + strconv.FormatUint(cents, 10)
+	}
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a decimal number of units, either as a JSON number or a string.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	amount, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+func abs(i int64) uint64 {
+	if i < 0 {
+		return uint64(-i)
+	}
+	return uint64(i)
+}
@@ -0,0 +1,232 @@
+//go:build unit
+
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		s           string
+		expected    Amount
+		expectedErr error
+	}{
+		"units":             {s: "200", expected: 20000},
+		"cents":             {s: "199.99", expected: 19999},
+		"single decimal":    {s: "8.5", expected: 850},
+		"trailing zeros":    {s: "8.500", expected: 850},
+		"no units":          {s: ".05", expected: 5},
+		"negative":          {s: "-3.1", expected: -310},
+		"exponent":          {s: "1.5E2", expected: 15000},
+		"negative exponent": {s: "1234e-2", expected: 1234},
+		"zero":              {s: "-0.000e-20", expected: 0},
+		"too many decimals": {s: "0.001", expectedErr: errPrecision},
+		"letters":           {s: "12a", expectedErr: errSyntax},
+		"empty":             {s: "", expectedErr: errSyntax},
+		"sign only":         {s: "-", expectedErr: errSyntax},
+		"bad exponent":      {s: "1e", expectedErr: errSyntax},
+		"too big":           {s: "92233720368547758.08", expectedErr: ErrRange},
+		"largest":           {s: "92233720368547758.07", expected: math.MaxInt64},
+		"huge exponent":     {s: "1e400", expectedErr: ErrRange},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse(tt.s)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	tests := map[string]struct {
+		amount   Amount
+		expected string
+	}{
+		"units":          {amount: 20000, expected: "200"},
+		"cents":          {amount: 19999, expected: "199.99"},
+		"tens of cents":  {amount: 850, expected: "8.5"},
+		"single cent":    {amount: 1005, expected: "10.05"},
+		"negative cents": {amount: -5, expected: "-0.05"},
+		"negative":       {amount: -310, expected: "-3.1"},
+		"zero":           {amount: 0, expected: "0"},
+		"smallest":       {amount: math.MinInt64, expected: "-92233720368547758.08"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.amount.String(); got != tt.expected {
+				t.Errorf("got: %s, expected: %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmount_MulDiv(t *testing.T) {
+	tests := map[string]struct {
+		amount   Amount
+		num, den int64
+		mode     RoundingMode
+		expected Amount
+	}{
+		"exact":                  {amount: 15600, num: 22, den: 100, mode: HalfEven, expected: 3432},
+		"half even down":         {amount: 5, num: 1, den: 2, mode: HalfEven, expected: 2},
+		"half even up":           {amount: 7, num: 1, den: 2, mode: HalfEven, expected: 4},
+		"half even above half":   {amount: 2, num: 1, den: 3, mode: HalfEven, expected: 1},
+		"half up":                {amount: 5, num: 1, den: 2, mode: HalfUp, expected: 3},
+		"half up below half":     {amount: 1, num: 1, den: 3, mode: HalfUp, expected: 0},
+		"negative half up":       {amount: -5, num: 1, den: 2, mode: HalfUp, expected: -3},
+		"down":                   {amount: 2, num: 1, den: 3, mode: Down, expected: 0},
+		"negative down":          {amount: -2, num: 1, den: 3, mode: Down, expected: 0},
+		"up":                     {amount: 1, num: 1, den: 3, mode: Up, expected: 1},
+		"negative up":            {amount: -1, num: 1, den: 3, mode: Up, expected: -1},
+		"floor":                  {amount: -1, num: 1, den: 3, mode: Floor, expected: -1},
+		"ceiling":                {amount: 1, num: 1, den: 3, mode: Ceiling, expected: 1},
+		"negative ceiling":       {amount: -1, num: 1, den: 3, mode: Ceiling, expected: 0},
+		"no overflow on product": {amount: math.MaxInt64, num: 100, den: 200, mode: Down, expected: math.MaxInt64 / 2},
+		"negative denominator":   {amount: 10, num: 1, den: -4, mode: HalfUp, expected: -3},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.amount.MulDiv(tt.num, tt.den, tt.mode); got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmount_CheckedAdd(t *testing.T) {
+	tests := map[string]struct {
+		a, b        Amount
+		expected    Amount
+		expectedErr error
+	}{
+		"in range":          {a: 15600, b: -600, expected: 15000},
+		"up to the max":     {a: math.MaxInt64 - 1, b: 1, expected: math.MaxInt64},
+		"overflow":          {a: math.MaxInt64, b: 1, expectedErr: ErrRange},
+		"underflow":         {a: math.MinInt64, b: -1, expectedErr: ErrRange},
+		"negative in range": {a: -1, b: math.MinInt64 + 1, expected: math.MinInt64},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.a.CheckedAdd(tt.b)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmount_CheckedMulDiv(t *testing.T) {
+	tests := map[string]struct {
+		amount      Amount
+		num, den    int64
+		expected    Amount
+		expectedErr error
+	}{
+		"in range":         {amount: 15600, num: 22, den: 100, expected: 3432},
+		"overflow":         {amount: math.MaxInt64, num: 2, den: 1, expectedErr: ErrRange},
+		"quotient too big": {amount: math.MaxInt64 / 2, num: 3, den: 1, expectedErr: ErrRange},
+		"division by zero": {amount: 1, num: 1, den: 0, expectedErr: errDivision},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.amount.CheckedMulDiv(tt.num, tt.den, HalfEven)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error: %v, expected: %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := map[string]struct {
+		f        float64
+		mode     RoundingMode
+		expected Amount
+	}{
+		"binary leftovers": {f: 8.29, mode: HalfUp, expected: 829},
+		"nearest":          {f: 5.7896, mode: HalfUp, expected: 579},
+		"down":             {f: 2.33291, mode: Down, expected: 233},
+		"up":               {f: 1.55789, mode: Up, expected: 156},
+		"negative floor":   {f: -1.551, mode: Floor, expected: -156},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := FromFloat(tt.f, tt.mode); got != tt.expected {
+				t.Errorf("got: %d, expected: %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var got struct {
+		Number Amount `json:"number"`
+		String Amount `json:"string"`
+	}
+	err := json.Unmarshal([]byte(`{"number": 199.99, "string": "1.5"}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Number != 19999 || got.String != 150 {
+		t.Errorf("got: %d %d, expected: 19999 150", got.Number, got.String)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"number":199.99,"string":1.5}`; string(data) != expected {
+		t.Errorf("got: %s, expected: %s", data, expected)
+	}
+
+	err = json.Unmarshal([]byte(`{"number": 0.001}`), &got)
+	if !errors.Is(err, errPrecision) {
+		t.Errorf("got error: %v, expected: %v", err, errPrecision)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add("199.99")
+	f.Fuzz(func(t *testing.T, s string) {
+		a, err := Parse(s)
+		if err != nil {
+			return
+		}
+		if back, err := Parse(a.String()); err != nil || back != a {
+			t.Errorf("%s parsed as %s, read back as %s: %v", s, a, back, err)
+		}
+	})
+}